@@ -0,0 +1,186 @@
+package slogutils
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrAsyncWriterClosed is returned by writes to a closed AsyncWriter.
+var ErrAsyncWriterClosed = errors.New("slogutils: async writer is closed")
+
+// OverflowPolicy decides what an AsyncWriter does when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the writer until there is room in the buffer.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the line being written.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest buffered line to make room.
+	OverflowDropOldest
+	// OverflowDropBelowLevel drops the line being written if its level is below AsyncWriterOptions.DropLevel,
+	// and blocks otherwise.
+	OverflowDropBelowLevel
+)
+
+// AsyncWriterOptions are options for NewAsyncWriter.
+type AsyncWriterOptions struct {
+	// BufferSize is the number of lines the buffer holds. If zero, 1024 is used.
+	BufferSize int
+
+	// OverflowPolicy decides what to do when the buffer is full.
+	OverflowPolicy OverflowPolicy
+
+	// DropLevel is the level below which lines are dropped under OverflowDropBelowLevel.
+	// If nil, slog.LevelWarn is used.
+	DropLevel slog.Leveler
+}
+
+// AsyncWriter is an io.Writer that buffers lines in a bounded ring buffer and writes them to the underlying writer
+// in a background goroutine, so that a slow writer does not stall the goroutines that log.
+// Call Close to write the buffered lines and stop the goroutine.
+type AsyncWriter struct {
+	w       io.Writer
+	opts    AsyncWriterOptions
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     []asyncLine
+	head    int
+	n       int
+	writing bool
+	closed  bool
+	err     error
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+type asyncLine struct {
+	level slog.Level
+	b     []byte
+}
+
+// NewAsyncWriter returns a new AsyncWriter that writes to w.
+func NewAsyncWriter(w io.Writer, opts AsyncWriterOptions) *AsyncWriter {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+	if opts.DropLevel == nil {
+		opts.DropLevel = slog.LevelWarn
+	}
+	aw := &AsyncWriter{
+		w:    w,
+		opts: opts,
+		buf:  make([]asyncLine, opts.BufferSize),
+		done: make(chan struct{}),
+	}
+	aw.cond = sync.NewCond(&aw.mu)
+	go aw.run()
+	return aw
+}
+
+// Write implements io.Writer. The line is treated as slog.LevelInfo.
+func (aw *AsyncWriter) Write(b []byte) (int, error) {
+	return aw.WriteLevel(slog.LevelInfo, b)
+}
+
+// WriteLevel writes a line of the given level. A Middleware calls it with the level of the record being written.
+func (aw *AsyncWriter) WriteLevel(l slog.Level, b []byte) (int, error) {
+	line := asyncLine{level: l, b: append([]byte(nil), b...)}
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	for {
+		if aw.closed {
+			return 0, ErrAsyncWriterClosed
+		}
+		if aw.n < len(aw.buf) {
+			break
+		}
+		switch aw.opts.OverflowPolicy {
+		case OverflowDropNewest:
+			aw.dropped.Add(1)
+			return len(b), nil
+		case OverflowDropOldest:
+			aw.head = (aw.head + 1) % len(aw.buf)
+			aw.n--
+			aw.dropped.Add(1)
+			continue
+		case OverflowDropBelowLevel:
+			if l < aw.opts.DropLevel.Level() {
+				aw.dropped.Add(1)
+				return len(b), nil
+			}
+		}
+		aw.cond.Wait()
+	}
+	aw.buf[(aw.head+aw.n)%len(aw.buf)] = line
+	aw.n++
+	aw.cond.Broadcast()
+	return len(b), nil
+}
+
+// Flush waits until all buffered lines are written, and returns the first write error since the last Flush.
+func (aw *AsyncWriter) Flush() error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	for aw.n > 0 || aw.writing {
+		aw.cond.Wait()
+	}
+	err := aw.err
+	aw.err = nil
+	return err
+}
+
+// Close writes all buffered lines and stops the background goroutine.
+// It does not close the underlying writer.
+func (aw *AsyncWriter) Close() error {
+	aw.mu.Lock()
+	if aw.closed {
+		aw.mu.Unlock()
+		return nil
+	}
+	aw.closed = true
+	aw.cond.Broadcast()
+	aw.mu.Unlock()
+	<-aw.done
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	err := aw.err
+	aw.err = nil
+	return err
+}
+
+// Dropped returns the number of lines dropped by the OverflowPolicy.
+func (aw *AsyncWriter) Dropped() uint64 {
+	return aw.dropped.Load()
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.done)
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	for {
+		for aw.n == 0 && !aw.closed {
+			aw.cond.Wait()
+		}
+		if aw.n == 0 {
+			return
+		}
+		line := aw.buf[aw.head]
+		aw.buf[aw.head] = asyncLine{}
+		aw.head = (aw.head + 1) % len(aw.buf)
+		aw.n--
+		aw.writing = true
+		aw.cond.Broadcast()
+		aw.mu.Unlock()
+		_, err := aw.w.Write(line.b)
+		aw.mu.Lock()
+		aw.writing = false
+		if err != nil && aw.err == nil {
+			aw.err = err
+		}
+		aw.cond.Broadcast()
+	}
+}
@@ -0,0 +1,101 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// gatedWriter blocks the first write until the gate is opened.
+type gatedWriter struct {
+	buf     bytes.Buffer
+	started chan struct{}
+	gate    chan struct{}
+	first   bool
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{
+		started: make(chan struct{}),
+		gate:    make(chan struct{}),
+	}
+}
+
+func (w *gatedWriter) Write(b []byte) (int, error) {
+	if !w.first {
+		w.first = true
+		close(w.started)
+		<-w.gate
+	}
+	return w.buf.Write(b)
+}
+
+func TestAsyncWriter__OverflowPolicy(t *testing.T) {
+	cases := []struct {
+		name            string
+		policy          OverflowPolicy
+		expected        string
+		expectedDropped uint64
+	}{
+		{"drop newest", OverflowDropNewest, "1\n2\n3\n", 2},
+		{"drop oldest", OverflowDropOldest, "1\n4\n5\n", 2},
+		{"drop below level", OverflowDropBelowLevel, "1\n2\n3\n", 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := newGatedWriter()
+			aw := NewAsyncWriter(w, AsyncWriterOptions{
+				BufferSize:     2,
+				OverflowPolicy: c.policy,
+			})
+			aw.Write([]byte("1\n"))
+			<-w.started
+			for _, line := range []string{"2\n", "3\n", "4\n", "5\n"} {
+				if _, err := aw.WriteLevel(slog.LevelInfo, []byte(line)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			close(w.gate)
+			if err := aw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if w.buf.String() != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, w.buf.String())
+			}
+			if aw.Dropped() != c.expectedDropped {
+				t.Errorf("expected %d dropped, got %d", c.expectedDropped, aw.Dropped())
+			}
+			if _, err := aw.Write([]byte("6\n")); err != ErrAsyncWriterClosed {
+				t.Errorf("expected ErrAsyncWriterClosed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMiddleware__WithAsync(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			Writer: buf,
+			Async:  &AsyncWriterOptions{BufferSize: 16},
+		},
+	)
+	logger := slog.New(middleware)
+	ctx := With(context.Background(), slog.Int64("request_id", 12))
+	for i := 0; i < 100; i++ {
+		logger.InfoContext(ctx, "foo", "i", i)
+	}
+	if err := middleware.Close(); err != nil {
+		t.Fatal(err)
+	}
+	actual := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(actual) != 100 {
+		t.Fatalf("expected 100 lines, got %d lines", len(actual))
+	}
+	if middleware.Dropped() != 0 {
+		t.Errorf("expected no dropped lines, got %d", middleware.Dropped())
+	}
+}
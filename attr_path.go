@@ -0,0 +1,121 @@
+package slogutils
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// attrPath is a dotted key path such as "http.request.headers.authorization".
+// full is the path as given by the user, rest is the part not yet matched while descending into groups.
+type attrPath struct {
+	full string
+	rest string
+}
+
+func newAttrPaths(keys []string) []attrPath {
+	paths := make([]attrPath, len(keys))
+	for i, key := range keys {
+		paths[i] = attrPath{full: key, rest: key}
+	}
+	return paths
+}
+
+// matchAttrPath returns the path that exactly addresses the attribute with the given key.
+func matchAttrPath(key string, paths []attrPath) (attrPath, bool) {
+	for _, p := range paths {
+		if p.rest == key {
+			return p, true
+		}
+	}
+	return attrPath{}, false
+}
+
+// subAttrPaths returns the paths that descend into the group with the given key.
+// A group with an empty key is inlined by handlers, so every path descends into it.
+func subAttrPaths(key string, paths []attrPath) []attrPath {
+	if key == "" {
+		return paths
+	}
+	var sub []attrPath
+	prefix := key + "."
+	for _, p := range paths {
+		if strings.HasPrefix(p.rest, prefix) {
+			sub = append(sub, attrPath{full: p.full, rest: p.rest[len(prefix):]})
+		}
+	}
+	return sub
+}
+
+// editAttrs walks attrs, descending into groups, and calls f for every attribute addressed by one of paths.
+// f returns the replacement attribute and whether to keep it.
+func editAttrs(attrs []slog.Attr, paths []attrPath, f func(slog.Attr, attrPath) (slog.Attr, bool)) []slog.Attr {
+	edited := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if p, ok := matchAttrPath(a.Key, paths); ok {
+			if a, keep := f(a, p); keep {
+				edited = append(edited, a)
+			}
+			continue
+		}
+		if sub := subAttrPaths(a.Key, paths); len(sub) > 0 {
+			if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+				a = slog.Attr{Key: a.Key, Value: slog.GroupValue(editAttrs(v.Group(), sub, f)...)}
+			}
+		}
+		edited = append(edited, a)
+	}
+	return edited
+}
+
+// hasAttrPath reports whether attrs contain an attribute addressed by path.
+func hasAttrPath(attrs []slog.Attr, path string) bool {
+	paths := newAttrPaths([]string{path})
+	for _, a := range attrs {
+		if _, ok := matchAttrPath(a.Key, paths); ok {
+			return true
+		}
+		if sub := subAttrPaths(a.Key, paths); len(sub) > 0 {
+			if v := a.Value.Resolve(); v.Kind() == slog.KindGroup && hasAttrPath(v.Group(), sub[0].rest) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// insertAttr adds a at path. If a prefix of path names an existing group, a is added into that group
+// under the rest of the path; otherwise a is appended with path as its key.
+func insertAttr(attrs []slog.Attr, path string, a slog.Attr) []slog.Attr {
+	for i := len(attrs) - 1; i >= 0; i-- {
+		g := attrs[i]
+		if g.Key == "" || !strings.HasPrefix(path, g.Key+".") {
+			continue
+		}
+		v := g.Value.Resolve()
+		if v.Kind() != slog.KindGroup {
+			continue
+		}
+		inserted := make([]slog.Attr, len(attrs))
+		copy(inserted, attrs)
+		inserted[i] = slog.Attr{Key: g.Key, Value: slog.GroupValue(insertAttr(v.Group(), path[len(g.Key)+1:], a)...)}
+		return inserted
+	}
+	return append(attrs, slog.Attr{Key: path, Value: a.Value})
+}
+
+// recordAttrs returns the attributes of r.
+func recordAttrs(r slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// withAttrs returns a copy of r whose attributes are replaced with attrs.
+func withAttrs(r slog.Record, attrs []slog.Attr) slog.Record {
+	c := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	c.AddAttrs(attrs...)
+	return c
+}
@@ -65,7 +65,8 @@ func BenchmarkMiddleware(b *testing.B) {
 					slog.LevelWarn:  Color(color.FgYellow),
 					slog.LevelError: Color(color.FgRed, color.BgBlack),
 				},
-				Writer: io.Discard,
+				Writer:    io.Discard,
+				ColorMode: ColorModeAlways,
 				HandlerOptions: &slog.HandlerOptions{
 					Level: slog.LevelWarn,
 				},
@@ -93,7 +94,8 @@ func BenchmarkMiddlewareWithRecordTrnasformer(b *testing.B) {
 				RecordTransformerFuncs: []RecordTransformerFunc{
 					DefaultAttrs("hoge", "fuga"),
 				},
-				Writer: io.Discard,
+				Writer:    io.Discard,
+				ColorMode: ColorModeAlways,
 				HandlerOptions: &slog.HandlerOptions{
 					Level: slog.LevelWarn,
 				},
@@ -118,7 +120,8 @@ func BenchmarkLogOutputWithMiddleware(b *testing.B) {
 					slog.LevelWarn:  Color(color.FgYellow),
 					slog.LevelError: Color(color.FgRed, color.BgBlack),
 				},
-				Writer: io.Discard,
+				Writer:    io.Discard,
+				ColorMode: ColorModeAlways,
 				HandlerOptions: &slog.HandlerOptions{
 					Level: slog.LevelWarn,
 				},
@@ -154,7 +157,8 @@ func BenchmarkLogOutputWithRecordTransformer(b *testing.B) {
 						},
 						false),
 				},
-				Writer: io.Discard,
+				Writer:    io.Discard,
+				ColorMode: ColorModeAlways,
 				HandlerOptions: &slog.HandlerOptions{
 					Level: slog.LevelWarn,
 				},
@@ -0,0 +1,109 @@
+package slogutils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ColorMode controls whether a Middleware applies its ModifierFuncs and whether a ConsoleHandler colors its output.
+type ColorMode int
+
+const (
+	// ColorModeAuto enables colors when the writer is a terminal, honoring the NO_COLOR, FORCE_COLOR,
+	// CLICOLOR_FORCE and CLICOLOR environment variables.
+	ColorModeAuto ColorMode = iota
+	// ColorModeAlways always enables colors.
+	ColorModeAlways
+	// ColorModeNever always disables colors.
+	ColorModeNever
+)
+
+// String implements fmt.Stringer.
+func (m ColorMode) String() string {
+	switch m {
+	case ColorModeAlways:
+		return "always"
+	case ColorModeNever:
+		return "never"
+	default:
+		return "auto"
+	}
+}
+
+// ParseColorMode parses "auto", "always" or "never", case-insensitively.
+// "true", "on" and "1" are accepted for always, and "false", "off" and "0" for never.
+func ParseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "auto", "":
+		return ColorModeAuto, nil
+	case "always", "true", "on", "1":
+		return ColorModeAlways, nil
+	case "never", "false", "off", "0":
+		return ColorModeNever, nil
+	}
+	return ColorModeAuto, fmt.Errorf("slogutils: invalid color mode %q: must be auto, always or never", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m ColorMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler by ParseColorMode.
+func (m *ColorMode) UnmarshalText(b []byte) error {
+	mode, err := ParseColorMode(string(b))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// Enabled reports whether colors are enabled for w.
+func (m ColorMode) Enabled(w io.Writer) bool {
+	switch m {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v := os.Getenv("FORCE_COLOR"); v != "" && v != "0" && v != "false" {
+		return true
+	}
+	if v := os.Getenv("CLICOLOR_FORCE"); v != "" && v != "0" {
+		return true
+	}
+	if os.Getenv("CLICOLOR") == "0" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// colorWriter is implemented by writers that already know whether their output should be colored,
+// such as the writer a Middleware passes to its handler.
+type colorWriter interface {
+	colorEnabled() bool
+}
+
+// colorEnabled reports whether output written to w should be colored.
+func colorEnabled(w io.Writer) bool {
+	if cw, ok := w.(colorWriter); ok {
+		return cw.colorEnabled()
+	}
+	return ColorModeAuto.Enabled(w)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
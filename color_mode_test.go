@@ -0,0 +1,94 @@
+package slogutils
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestColorMode__Enabled(t *testing.T) {
+	cases := []struct {
+		name     string
+		mode     ColorMode
+		env      map[string]string
+		expected bool
+	}{
+		{"always", ColorModeAlways, map[string]string{"NO_COLOR": "1"}, true},
+		{"never", ColorModeNever, map[string]string{"FORCE_COLOR": "1"}, false},
+		{"auto not terminal", ColorModeAuto, nil, false},
+		{"auto force color", ColorModeAuto, map[string]string{"FORCE_COLOR": "1"}, true},
+		{"auto force color zero", ColorModeAuto, map[string]string{"FORCE_COLOR": "0"}, false},
+		{"auto clicolor force", ColorModeAuto, map[string]string{"CLICOLOR_FORCE": "1"}, true},
+		{"auto no color wins", ColorModeAuto, map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, key := range []string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR"} {
+				t.Setenv(key, c.env[key])
+			}
+			if actual := c.mode.Enabled(new(bytes.Buffer)); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestColorMode__EnabledFile(t *testing.T) {
+	for _, key := range []string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR"} {
+		t.Setenv(key, "")
+	}
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if ColorModeAuto.Enabled(f) {
+		t.Errorf("expected colors to be disabled for a regular file")
+	}
+}
+
+func TestMiddleware__ColorModeAutoSkipsModifierFuncs(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "")
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		NewConsoleHandler,
+		MiddlewareOptions{
+			ModifierFuncs: map[slog.Level]ModifierFunc{
+				slog.LevelInfo: Color(color.FgBlue),
+			},
+			Writer: buf,
+		},
+	)
+	slog.New(middleware).Info("foo", "bar", "baz")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected no escape sequences, got %q", buf.String())
+	}
+}
+
+func TestParseColorMode(t *testing.T) {
+	cases := map[string]ColorMode{
+		"auto":   ColorModeAuto,
+		"":       ColorModeAuto,
+		"Always": ColorModeAlways,
+		"true":   ColorModeAlways,
+		"never":  ColorModeNever,
+		"0":      ColorModeNever,
+	}
+	for s, expected := range cases {
+		var m ColorMode
+		if err := m.UnmarshalText([]byte(s)); err != nil {
+			t.Errorf("%q: unexpected error: %s", s, err)
+		} else if m != expected {
+			t.Errorf("%q: expected %v, got %v", s, expected, m)
+		}
+	}
+	if _, err := ParseColorMode("rainbow"); err == nil {
+		t.Error("expected error")
+	}
+}
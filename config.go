@@ -0,0 +1,276 @@
+package slogutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Config describes a Middleware declaratively, for NewMiddlewareFromConfig.
+// It is decoded from JSON by LoadConfig, or from YAML by passing a YAML library's Unmarshal as the decoder.
+//
+// Example:
+//
+//	{
+//		"level": "info",
+//		"logger_levels": "db=debug",
+//		"format": "json",
+//		"output": "stderr",
+//		"colors": {"warn": ["yellow"], "error": ["red", "bold"]},
+//		"transformers": [
+//			{"type": "drop", "params": {"keys": ["password"]}},
+//			{"type": "legacy_level"}
+//		]
+//	}
+type Config struct {
+	// Level is the minimum level, such as "debug" or "info+2". If empty, the level of the base options is kept.
+	Level string `json:"level,omitempty" yaml:"level,omitempty"`
+
+	// LoggerLevels are per-logger levels in the format of ParseLoggerLevels.
+	LoggerLevels string `json:"logger_levels,omitempty" yaml:"logger_levels,omitempty"`
+
+	// Format is "json", "text" or "console". If empty, "json" is used.
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+
+	// Output is "stderr", "stdout" or a file path to append to. If empty, the Writer of the base options is used, or stderr.
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+
+	// Color is "auto", "always" or "never". If nil, the ColorMode of the base options is kept.
+	Color *ColorMode `json:"color,omitempty" yaml:"color,omitempty"`
+
+	// AddSource adds the source of each record.
+	AddSource bool `json:"add_source,omitempty" yaml:"add_source,omitempty"`
+
+	// Colors maps levels to color names, such as "red", "hi_yellow" or "bold", as ModifierFuncs.
+	Colors map[string][]string `json:"colors,omitempty" yaml:"colors,omitempty"`
+
+	// Transformers are the RecordTransformerFuncs, created by the transformers registered with RegisterTransformer.
+	Transformers []TransformerConfig `json:"transformers,omitempty" yaml:"transformers,omitempty"`
+}
+
+// TransformerConfig names a registered transformer and its params.
+type TransformerConfig struct {
+	Type   string         `json:"type" yaml:"type"`
+	Params map[string]any `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+var colorAttributes = map[string]color.Attribute{
+	"bold":       color.Bold,
+	"faint":      color.Faint,
+	"italic":     color.Italic,
+	"underline":  color.Underline,
+	"black":      color.FgBlack,
+	"red":        color.FgRed,
+	"green":      color.FgGreen,
+	"yellow":     color.FgYellow,
+	"blue":       color.FgBlue,
+	"magenta":    color.FgMagenta,
+	"cyan":       color.FgCyan,
+	"white":      color.FgWhite,
+	"hi_black":   color.FgHiBlack,
+	"hi_red":     color.FgHiRed,
+	"hi_green":   color.FgHiGreen,
+	"hi_yellow":  color.FgHiYellow,
+	"hi_blue":    color.FgHiBlue,
+	"hi_magenta": color.FgHiMagenta,
+	"hi_cyan":    color.FgHiCyan,
+	"hi_white":   color.FgHiWhite,
+}
+
+// LoadConfig reads the config file at path and decodes it by decode, such as yaml.Unmarshal.
+// If decode is nil, the file is decoded as JSON, and unknown fields are an error.
+func LoadConfig(path string, decode func([]byte, any) error) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if decode == nil {
+		decode = decodeStrictJSON
+	}
+	if err := decode(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("slogutils: invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func decodeStrictJSON(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// NewMiddlewareFromConfig returns a Middleware configured by cfg on top of opts.
+// The transformers of cfg are applied after opts.RecordTransformerFuncs.
+// Call Middleware.Close to close the file opened for Config.Output.
+func NewMiddlewareFromConfig(cfg Config, opts MiddlewareOptions) (*Middleware[slog.Handler], error) {
+	f, opts, closer, err := cfg.middlewareOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	m := NewMiddleware(f, opts)
+	m.core.closer = closer
+	return m, nil
+}
+
+// ApplyConfig reconfigures m, and all handlers derived from it, by cfg on top of opts.
+// It replaces the handler, level, writer and transformers at once, so a level set by SetMinLevel is replaced too.
+// If cfg is invalid, m is not changed.
+func ApplyConfig(m *Middleware[slog.Handler], cfg Config, opts MiddlewareOptions) error {
+	f, opts, closer, err := cfg.middlewareOptions(opts)
+	if err != nil {
+		return err
+	}
+	nc := newMiddlewareCore(f, opts)
+	nc.closer = closer
+	return m.core.reload(nc)
+}
+
+func (cfg Config) middlewareOptions(opts MiddlewareOptions) (HandlerConstructor, MiddlewareOptions, io.Closer, error) {
+	handlerOptions := slog.HandlerOptions{}
+	if opts.HandlerOptions != nil {
+		handlerOptions = *opts.HandlerOptions
+	}
+	opts.HandlerOptions = &handlerOptions
+	var errs []error
+	if cfg.Level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(cfg.Level)); err != nil {
+			errs = append(errs, fmt.Errorf("invalid level %q: %w", cfg.Level, err))
+		}
+		opts.HandlerOptions.Level = l
+	}
+	if cfg.LoggerLevels != "" {
+		ll, err := ParseLoggerLevels(cfg.LoggerLevels)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		opts.LoggerLevels = ll
+	}
+	format := cfg.Format
+	if format == "" {
+		format = "json"
+	}
+	f, err := HandlerConstructorForFormat(format)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Color != nil {
+		opts.ColorMode = *cfg.Color
+	}
+	opts.HandlerOptions.AddSource = opts.HandlerOptions.AddSource || cfg.AddSource
+	if len(cfg.Colors) > 0 {
+		modifierFuncs := make(map[slog.Level]ModifierFunc, len(opts.ModifierFuncs)+len(cfg.Colors))
+		for l, mf := range opts.ModifierFuncs {
+			modifierFuncs[l] = mf
+		}
+		levels := make([]string, 0, len(cfg.Colors))
+		for level := range cfg.Colors {
+			levels = append(levels, level)
+		}
+		sort.Strings(levels)
+		for _, level := range levels {
+			var l slog.Level
+			if err := l.UnmarshalText([]byte(level)); err != nil {
+				errs = append(errs, fmt.Errorf("invalid colors level %q: %w", level, err))
+				continue
+			}
+			attrs := make([]color.Attribute, 0, len(cfg.Colors[level]))
+			for _, name := range cfg.Colors[level] {
+				attr, ok := colorAttributes[strings.ToLower(name)]
+				if !ok {
+					errs = append(errs, fmt.Errorf("invalid color %q for level %s", name, level))
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			if len(attrs) == 0 {
+				modifierFuncs[l] = nil
+				continue
+			}
+			modifierFuncs[l] = Color(attrs...)
+		}
+		opts.ModifierFuncs = modifierFuncs
+	}
+	transformers := opts.RecordTransformerFuncs[:len(opts.RecordTransformerFuncs):len(opts.RecordTransformerFuncs)]
+	for _, tc := range cfg.Transformers {
+		t, err := NewTransformer(tc.Type, tc.Params)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		transformers = append(transformers, t)
+	}
+	opts.RecordTransformerFuncs = transformers
+	if len(errs) > 0 {
+		return nil, opts, nil, fmt.Errorf("slogutils: invalid config: %w", errors.Join(errs...))
+	}
+	w, closer, err := openOutput(cfg.Output, opts.Writer)
+	if err != nil {
+		return nil, opts, nil, fmt.Errorf("slogutils: invalid config output: %w", err)
+	}
+	opts.Writer = w
+	return f, opts, closer, nil
+}
+
+// WatchConfigOptions are options for WatchConfig.
+type WatchConfigOptions struct {
+	// Base are the options the config is applied on top of.
+	Base MiddlewareOptions
+
+	// Decode decodes the config file, such as yaml.Unmarshal. If nil, the file is decoded as JSON.
+	Decode func([]byte, any) error
+
+	// Interval is how often the file is checked for changes. If zero, 2 seconds is used.
+	Interval time.Duration
+
+	// OnReload is called after each reload with its error, if any. If the config is invalid, the Middleware is not changed.
+	OnReload func(error)
+}
+
+// WatchConfig reloads the config file at path into m by ApplyConfig whenever the file changes.
+// Call the returned function to stop watching.
+func WatchConfig(m *Middleware[slog.Handler], path string, opts WatchConfigOptions) (stop func()) {
+	if opts.Interval <= 0 {
+		opts.Interval = 2 * time.Second
+	}
+	last, _ := os.Stat(path)
+	ticker := time.NewTicker(opts.Interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			info, err := os.Stat(path)
+			if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+				continue
+			}
+			last = info
+			cfg, err := LoadConfig(path, opts.Decode)
+			if err == nil {
+				err = ApplyConfig(m, cfg, opts.Base)
+			}
+			if opts.OnReload != nil {
+				opts.OnReload(err)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
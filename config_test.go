@@ -0,0 +1,168 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func removeTimeAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func TestNewMiddlewareFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	os.WriteFile(path, []byte(`{
+		"level": "warn",
+		"logger_levels": "db=debug",
+		"format": "text",
+		"color": "always",
+		"colors": {"error": ["red", "bold"]},
+		"transformers": [
+			{"type": "drop", "params": {"keys": ["password"]}},
+			{"type": "legacy_level"}
+		]
+	}`), 0644)
+	cfg, err := LoadConfig(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	m, err := NewMiddlewareFromConfig(cfg, MiddlewareOptions{
+		Writer:         buf,
+		HandlerOptions: &slog.HandlerOptions{ReplaceAttr: removeTimeAttr},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	logger := slog.New(m)
+	logger.Info("dropped")
+	logger.Info("[WARN] legacy", "password", "secret")
+	logger.With(LoggerKey, "db").Debug("query")
+	logger.Error("boom")
+	expected := strings.Join([]string{
+		`level=WARN msg=legacy`,
+		`level=DEBUG msg=query logger=db`,
+		"\x1b[31;1mlevel=ERROR msg=boom\n\x1b[0m",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestLoadConfig__Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.json")
+	os.WriteFile(path, []byte(`{"levle": "debug"}`), 0644)
+	if _, err := LoadConfig(path, nil); err == nil || !strings.Contains(err.Error(), "levle") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	cfg := Config{
+		Level:        "loud",
+		Format:       "xml",
+		Colors:       map[string][]string{"warn": {"purple"}},
+		Transformers: []TransformerConfig{{Type: "nope"}},
+	}
+	_, err := NewMiddlewareFromConfig(cfg, MiddlewareOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, s := range []string{`invalid level "loud"`, `invalid format "xml"`, `invalid color "purple"`, `unknown transformer "nope"`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected error containing %q, got %s", s, err)
+		}
+	}
+}
+
+func TestNewMiddlewareFromConfig__Color(t *testing.T) {
+	never := ColorModeNever
+	cases := []struct {
+		name     string
+		color    *ColorMode
+		expected bool
+	}{
+		{"base kept", nil, true},
+		{"overridden", &never, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			m, err := NewMiddlewareFromConfig(Config{
+				Color:  c.color,
+				Colors: map[string][]string{"info": {"red"}},
+			}, MiddlewareOptions{ColorMode: ColorModeAlways, Writer: buf})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			slog.New(m).Info("hello")
+			if actual := strings.Contains(buf.String(), "\x1b["); actual != c.expected {
+				t.Errorf("expected colored %v, got %q", c.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	os.WriteFile(path, []byte(`{"level": "info", "format": "text"}`), 0644)
+	cfg, err := LoadConfig(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	base := MiddlewareOptions{
+		Writer:         buf,
+		HandlerOptions: &slog.HandlerOptions{ReplaceAttr: removeTimeAttr},
+	}
+	m, err := NewMiddlewareFromConfig(cfg, base)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	reloaded := make(chan error, 10)
+	stop := WatchConfig(m, path, WatchConfigOptions{
+		Base:     base,
+		Interval: 10 * time.Millisecond,
+		OnReload: func(err error) { reloaded <- err },
+	})
+	defer stop()
+	logger := slog.New(m).With("app", "test")
+	logger.Debug("before")
+
+	os.WriteFile(path, []byte(`{"level": "loud"}`), 0644)
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	if err := waitReload(t, reloaded); err == nil {
+		t.Fatal("expected reload error")
+	}
+	logger.Debug("invalid")
+
+	os.WriteFile(path, []byte(`{"level": "debug", "format": "json", "transformers": [{"type": "drop", "params": {"keys": ["app"]}}]}`), 0644)
+	os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second))
+	if err := waitReload(t, reloaded); err != nil {
+		t.Fatalf("unexpected reload error: %s", err)
+	}
+	logger.DebugContext(context.Background(), "after")
+
+	expected := `{"level":"DEBUG","msg":"after"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func waitReload(t *testing.T, reloaded chan error) error {
+	t.Helper()
+	select {
+	case err := <-reloaded:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded")
+		return nil
+	}
+}
@@ -0,0 +1,249 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// ConsoleTimeFormat is the layout of timestamps written by ConsoleHandler.
+const ConsoleTimeFormat = "2006-01-02 15:04:05.000"
+
+var (
+	consoleFaint      = newConsoleColor(color.Faint)
+	consoleLevelColor = map[slog.Level]*color.Color{
+		slog.LevelDebug: newConsoleColor(color.FgMagenta),
+		slog.LevelInfo:  newConsoleColor(color.FgGreen),
+		slog.LevelWarn:  newConsoleColor(color.FgYellow),
+		slog.LevelError: newConsoleColor(color.FgRed, color.Bold),
+	}
+)
+
+func newConsoleColor(attr ...color.Attribute) *color.Color {
+	c := color.New(attr...)
+	c.EnableColor()
+	return c
+}
+
+// ConsoleHandler is a slog.Handler that writes human-friendly lines for terminals, such as
+//
+//	2023-08-15 12:34:56.789 WARN  retrying request attempt=3 http.method=GET
+//
+// The level is colored, keys and values are dimmed, and multi-line values such as stack traces
+// are written on the following lines, indented.
+// NewConsoleHandler can be passed to NewMiddleware, in which case colors follow MiddlewareOptions.ColorMode.
+// Otherwise colors are enabled as ColorModeAuto decides for w.
+type ConsoleHandler struct {
+	opts     slog.HandlerOptions
+	mu       *sync.Mutex
+	w        io.Writer
+	colorize bool
+	goas     []groupOrAttrs
+}
+
+// NewConsoleHandler returns a ConsoleHandler that writes to w, using the given options.
+// If opts is nil, the default options are used.
+func NewConsoleHandler(w io.Writer, opts *slog.HandlerOptions) *ConsoleHandler {
+	h := &ConsoleHandler{
+		mu:       &sync.Mutex{},
+		w:        w,
+		colorize: colorEnabled(w),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *ConsoleHandler) Enabled(_ context.Context, l slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return l >= minLevel
+}
+
+// WithAttrs implements slog.Handler.
+func (h *ConsoleHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	if len(as) == 0 {
+		return h
+	}
+	c := *h
+	c.goas = append(append(make([]groupOrAttrs, 0, len(h.goas)+1), h.goas...), groupOrAttrs{attrs: as})
+	return &c
+}
+
+// WithGroup implements slog.Handler.
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.goas = append(append(make([]groupOrAttrs, 0, len(h.goas)+1), h.goas...), groupOrAttrs{group: name})
+	return &c
+}
+
+// consoleState accumulates a line and the multi-line values written after it.
+type consoleState struct {
+	h      *ConsoleHandler
+	line   bytes.Buffer
+	blocks bytes.Buffer
+}
+
+// Handle implements slog.Handler.
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	s := &consoleState{h: h}
+	if !r.Time.IsZero() {
+		if a := h.replace(nil, slog.Time(slog.TimeKey, r.Time)); a.Key != "" {
+			if v := a.Value.Resolve(); v.Kind() == slog.KindTime {
+				s.line.WriteString(h.paint(consoleFaint, v.Time().Format(ConsoleTimeFormat)))
+			} else {
+				s.line.WriteString(h.paint(consoleFaint, v.String()))
+			}
+			s.line.WriteByte(' ')
+		}
+	}
+	if a := h.replace(nil, slog.Any(slog.LevelKey, r.Level)); a.Key != "" {
+		s.line.WriteString(h.levelBadge(a.Value))
+		s.line.WriteByte(' ')
+	}
+	if a := h.replace(nil, slog.String(slog.MessageKey, r.Message)); a.Key != "" {
+		s.line.WriteString(a.Value.String())
+	}
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		s.appendAttr(nil, "", slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", f.File, f.Line)))
+	}
+
+	var groups []string
+	prefix := ""
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			groups = append(groups, goa.group)
+			prefix += goa.group + "."
+			continue
+		}
+		for _, a := range goa.attrs {
+			s.appendAttr(groups, prefix, a)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		s.appendAttr(groups, prefix, a)
+		return true
+	})
+	s.line.WriteByte('\n')
+	s.line.Write(s.blocks.Bytes())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(s.line.Bytes())
+	return err
+}
+
+func (h *ConsoleHandler) paint(c *color.Color, s string) string {
+	if !h.colorize {
+		return s
+	}
+	return c.Sprint(s)
+}
+
+func (h *ConsoleHandler) replace(groups []string, a slog.Attr) slog.Attr {
+	if h.opts.ReplaceAttr == nil || a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+	return h.opts.ReplaceAttr(groups, a)
+}
+
+func (s *consoleState) appendAttr(groups []string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	a = s.h.replace(groups, a)
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key == "" {
+			for _, ga := range a.Value.Group() {
+				s.appendAttr(groups, prefix, ga)
+			}
+			return
+		}
+		subGroups := append(groups[:len(groups):len(groups)], a.Key)
+		for _, ga := range a.Value.Group() {
+			s.appendAttr(subGroups, prefix+a.Key+".", ga)
+		}
+		return
+	}
+	key := prefix + a.Key
+	value := consoleValue(a.Value)
+	if strings.Contains(value, "\n") {
+		s.blocks.WriteString("  ")
+		s.blocks.WriteString(s.h.paint(consoleFaint, key+":"))
+		s.blocks.WriteByte('\n')
+		for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+			s.blocks.WriteString("    ")
+			s.blocks.WriteString(line)
+			s.blocks.WriteByte('\n')
+		}
+		return
+	}
+	s.line.WriteByte(' ')
+	s.line.WriteString(s.h.paint(consoleFaint, key+"="+quoteConsoleValue(value)))
+}
+
+// consoleValue formats v. Errors are formatted with %+v, so that errors carrying stack traces render them,
+// and a *slog.Source, such as the one added by AddSource, as file:line.
+func consoleValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return fmt.Sprintf("%+v", err)
+		}
+		if src, ok := v.Any().(*slog.Source); ok {
+			return fmt.Sprintf("%s:%d", src.File, src.Line)
+		}
+	}
+	return v.String()
+}
+
+func quoteConsoleValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// levelBadge returns the colored, fixed-width level of v.
+func (h *ConsoleHandler) levelBadge(v slog.Value) string {
+	l, ok := v.Any().(slog.Level)
+	if !ok {
+		return v.String()
+	}
+	badge := fmt.Sprintf("%-5s", l.String())
+	c := consoleLevelColor[slog.LevelDebug]
+	for _, base := range []slog.Level{slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		if l >= base {
+			c = consoleLevelColor[base]
+		}
+	}
+	return h.paint(c, badge)
+}
@@ -0,0 +1,66 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewConsoleHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(h).With("app", "foo").WithGroup("http")
+	r := slog.NewRecord(time.Date(2023, 8, 15, 12, 34, 56, 789000000, time.UTC), slog.LevelWarn, "retrying request", 0)
+	r.AddAttrs(
+		slog.Int("attempt", 3),
+		slog.String("path", "/foo bar"),
+		slog.Group("request", slog.String("method", "GET")),
+		slog.Any("error", errors.New("connection refused\ngoroutine 1 [running]:\nmain.main()")),
+	)
+	if err := logger.Handler().Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		`2023-08-15 12:34:56.789 WARN  retrying request app=foo http.attempt=3 http.path="/foo bar" http.request.method=GET`,
+		`  http.error:`,
+		`    connection refused`,
+		`    goroutine 1 [running]:`,
+		`    main.main()`,
+		``,
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestConsoleHandler__WithMiddleware(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		NewConsoleHandler,
+		MiddlewareOptions{
+			Writer:    buf,
+			ColorMode: ColorModeAlways,
+			HandlerOptions: &slog.HandlerOptions{
+				Level: slog.LevelInfo,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey && len(groups) == 0 {
+						return slog.Attr{}
+					}
+					return a
+				},
+			},
+		},
+	)
+	logger := slog.New(middleware)
+	ctx := With(context.Background(), slog.Int64("request_id", 12))
+	logger.DebugContext(ctx, "baz")
+	logger.ErrorContext(ctx, "bar")
+	expected := "\x1b[31;1mERROR\x1b[0m bar \x1b[2mrequest_id=12\x1b[0m\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,140 @@
+package slogutils
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRepeatCountKey is the default key of the attribute that holds the number of collapsed records.
+const DefaultRepeatCountKey = "repeat_count"
+
+// DedupOptions are options for NewDeduplicator.
+type DedupOptions struct {
+	// Window is the period, from the first record of a burst, in which identical records are collapsed
+	// even if other records are written in between. It is measured by Now rather than by the time of records.
+	// If zero, only consecutive identical records are collapsed.
+	//
+	// The repeat count of a burst is due when its Window ends, but it is written only when the Middleware asks for it:
+	// with the next handled record, by Middleware.Flush, or every MiddlewareOptions.EmitInterval.
+	// Without EmitInterval, the repeat count of a burst that stopped is not written until the next record or Flush.
+	Window time.Duration
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	// RepeatCountKey is the key of the attribute that holds the number of collapsed records.
+	// If empty, DefaultRepeatCountKey is used.
+	RepeatCountKey string
+}
+
+// Deduplicator is a RecordProcessor that collapses repeated records with identical level, message and attributes.
+// The first record of a burst is written as is. When the burst ends, the last repeated record is emitted
+// with an attribute holding the number of records collapsed after the first one.
+// Unlike UniqueAttrs, it deduplicates across records rather than within one.
+type Deduplicator struct {
+	mu      sync.Mutex
+	opts    DedupOptions
+	last    string
+	bursts  map[string]*dedupBurst
+	pending []slog.Record
+}
+
+type dedupBurst struct {
+	start  time.Time
+	record slog.Record
+	count  int
+}
+
+// NewDeduplicator returns a new Deduplicator.
+func NewDeduplicator(opts DedupOptions) *Deduplicator {
+	if opts.RepeatCountKey == "" {
+		opts.RepeatCountKey = DefaultRepeatCountKey
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Deduplicator{
+		opts:   opts,
+		bursts: make(map[string]*dedupBurst),
+	}
+}
+
+// ProcessRecord implements RecordProcessor.
+func (d *Deduplicator) ProcessRecord(_ context.Context, r slog.Record) (slog.Record, bool, error) {
+	now := d.opts.Now()
+	key := dedupKey(r)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.opts.Window > 0 {
+		d.expire(now)
+	} else if key != d.last {
+		d.expire(time.Time{})
+	}
+	d.last = key
+	if b, ok := d.bursts[key]; ok {
+		b.record = r
+		b.count++
+		return r, false, nil
+	}
+	d.bursts[key] = &dedupBurst{start: now, record: r}
+	return r, true, nil
+}
+
+// EmitRecords implements RecordEmitter.
+func (d *Deduplicator) EmitRecords(flush bool) []slog.Record {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if flush {
+		d.expire(time.Time{})
+		d.last = ""
+	} else if d.opts.Window > 0 {
+		d.expire(d.opts.Now())
+	}
+	pending := d.pending
+	d.pending = nil
+	return pending
+}
+
+// expire ends the bursts that started a Window or more before now.
+// If now is zero, all bursts end.
+func (d *Deduplicator) expire(now time.Time) {
+	var ended []*dedupBurst
+	for key, b := range d.bursts {
+		if !now.IsZero() && now.Sub(b.start) < d.opts.Window {
+			continue
+		}
+		delete(d.bursts, key)
+		if b.count > 0 {
+			ended = append(ended, b)
+		}
+	}
+	sort.Slice(ended, func(i, j int) bool { return ended[i].start.Before(ended[j].start) })
+	for _, b := range ended {
+		c := b.record.Clone()
+		c.AddAttrs(slog.Int(d.opts.RepeatCountKey, b.count))
+		d.pending = append(d.pending, c)
+	}
+}
+
+// dedupKey returns a string that identifies records with the same level, message and attributes.
+// The time attribute added by FormatTime is ignored, but other attributes with the key "time" are not.
+func dedupKey(r slog.Record) string {
+	var b strings.Builder
+	b.WriteString(r.Level.String())
+	b.WriteByte(0)
+	b.WriteString(r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		if _, ok := a.Value.Any().(formattedTime); ok {
+			return true
+		}
+		b.WriteByte(0)
+		a.Value = a.Value.Resolve()
+		b.WriteString(a.String())
+		return true
+	})
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMiddleware__WithDedup(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     DedupOptions
+		expected []string
+	}{
+		{
+			name: "consecutive",
+			opts: DedupOptions{},
+			expected: []string{
+				`level=WARN msg=retrying worker=1`,
+				`level=WARN msg=retrying worker=1 repeat_count=2`,
+				`level=INFO msg=done`,
+				`level=WARN msg=retrying worker=1`,
+				`level=INFO msg=stopped`,
+			},
+		},
+		{
+			name: "within window",
+			opts: DedupOptions{Window: time.Minute, RepeatCountKey: "repeated"},
+			expected: []string{
+				`level=WARN msg=retrying worker=1`,
+				`level=INFO msg=done`,
+				`level=WARN msg=retrying worker=1 repeated=3`,
+				`level=INFO msg=stopped`,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			now := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+			clock := now
+			c.opts.Now = func() time.Time { return clock }
+			middleware := NewMiddleware(
+				slog.NewTextHandler,
+				MiddlewareOptions{
+					Dedup:  &c.opts,
+					Writer: buf,
+					HandlerOptions: &slog.HandlerOptions{
+						ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+							if a.Key == slog.TimeKey && len(groups) == 0 {
+								return slog.Attr{}
+							}
+							return a
+						},
+					},
+				},
+			)
+			ctx := context.Background()
+			logger := slog.New(middleware)
+			handle := func(at time.Duration, level slog.Level, msg string, args ...any) {
+				t.Helper()
+				clock = now.Add(at)
+				r := slog.NewRecord(clock, level, msg, 0)
+				r.Add(args...)
+				if err := logger.Handler().Handle(ctx, r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			handle(0, slog.LevelWarn, "retrying", "worker", 1)
+			handle(time.Second, slog.LevelWarn, "retrying", "worker", 1)
+			handle(2*time.Second, slog.LevelDebug, "ignored")
+			handle(3*time.Second, slog.LevelWarn, "retrying", "worker", 1)
+			handle(4*time.Second, slog.LevelInfo, "done")
+			handle(5*time.Second, slog.LevelWarn, "retrying", "worker", 1)
+			handle(2*time.Minute, slog.LevelInfo, "stopped")
+			if err := middleware.Flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+			actual := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if strings.Join(actual, "\n") != strings.Join(c.expected, "\n") {
+				t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(c.expected, "\n"), buf.String())
+			}
+		})
+	}
+}
+
+func TestDedup__TimeAttr(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewMiddleware(slog.NewTextHandler, MiddlewareOptions{
+		Dedup:  &DedupOptions{},
+		Writer: buf,
+	}))
+	at := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+	logger.Info("scheduled", "time", at)
+	logger.Info("scheduled", "time", at.Add(time.Hour))
+	if n := strings.Count(buf.String(), "msg=scheduled"); n != 2 {
+		t.Errorf("expected records with different time attributes to be written, got %s", buf.String())
+	}
+}
+
+func TestDedup__EmitInterval(t *testing.T) {
+	buf := new(lockedBuffer)
+	var mu sync.Mutex
+	now := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+	middleware := NewMiddleware(slog.NewTextHandler, MiddlewareOptions{
+		RecordTransformerFuncs: []RecordTransformerFunc{
+			FixedTime(time.Time{}),
+		},
+		Dedup: &DedupOptions{
+			Window: time.Minute,
+			Now: func() time.Time {
+				mu.Lock()
+				defer mu.Unlock()
+				return now
+			},
+		},
+		EmitInterval: time.Millisecond,
+		Writer:       buf,
+	})
+	defer middleware.Close()
+	logger := slog.New(middleware)
+	for i := 0; i < 3; i++ {
+		logger.Warn("retrying")
+	}
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	expected := "level=WARN msg=retrying repeat_count=2"
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), expected) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s without another record, got %q", expected, buf.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
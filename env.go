@@ -0,0 +1,137 @@
+package slogutils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HandlerConstructor creates the slog.Handler of a Middleware, such as slog.NewJSONHandler.
+type HandlerConstructor func(io.Writer, *slog.HandlerOptions) slog.Handler
+
+// HandlerConstructorForFormat returns the HandlerConstructor for the format "json", "text" or "console".
+func HandlerConstructorForFormat(format string) (HandlerConstructor, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewJSONHandler(w, opts)
+		}, nil
+	case "text":
+		return func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewTextHandler(w, opts)
+		}, nil
+	case "console":
+		return func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return NewConsoleHandler(w, opts)
+		}, nil
+	}
+	return nil, fmt.Errorf("slogutils: invalid format %q: must be json, text or console", format)
+}
+
+// NewMiddlewareFromEnv returns a Middleware configured by opts and the following environment variables,
+// which override opts when set:
+//
+//	LOG_LEVEL       minimum level, such as debug, info, warn, error or info+2 (default: info)
+//	LOG_LEVELS      per-logger levels for MiddlewareOptions.LoggerLevels, such as db=debug,http.client=warn
+//	LOG_FORMAT      json, text or console (default: json)
+//	LOG_COLOR       auto, always or never (default: auto)
+//	LOG_OUTPUT      stderr, stdout or a file path to append to (default: stderr)
+//	LOG_ADD_SOURCE  true or false (default: false)
+//	LOG_DROP_KEYS   comma-separated keys or dotted paths to drop by DropAttrs
+//
+// It returns an error that names every invalid variable.
+// Call Middleware.Close to close the file opened for LOG_OUTPUT.
+func NewMiddlewareFromEnv(opts MiddlewareOptions) (*Middleware[slog.Handler], error) {
+	handlerOptions := slog.HandlerOptions{}
+	if opts.HandlerOptions != nil {
+		handlerOptions = *opts.HandlerOptions
+	}
+	opts.HandlerOptions = &handlerOptions
+	var errs []error
+	if v, ok := lookupEnv("LOG_LEVEL"); ok {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(v)); err != nil {
+			errs = append(errs, fmt.Errorf("invalid LOG_LEVEL %q: %w", v, err))
+		}
+		opts.HandlerOptions.Level = l
+	}
+	if v, ok := lookupEnv("LOG_LEVELS"); ok {
+		ll, err := ParseLoggerLevels(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid LOG_LEVELS %q: %w", v, err))
+		}
+		opts.LoggerLevels = ll
+	}
+	format := "json"
+	if v, ok := lookupEnv("LOG_FORMAT"); ok {
+		format = v
+	}
+	f, err := HandlerConstructorForFormat(format)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid LOG_FORMAT: %w", err))
+	}
+	if v, ok := lookupEnv("LOG_COLOR"); ok {
+		if opts.ColorMode, err = ParseColorMode(v); err != nil {
+			errs = append(errs, fmt.Errorf("invalid LOG_COLOR: %w", err))
+		}
+	}
+	if v, ok := lookupEnv("LOG_ADD_SOURCE"); ok {
+		if opts.HandlerOptions.AddSource, err = strconv.ParseBool(v); err != nil {
+			errs = append(errs, fmt.Errorf("invalid LOG_ADD_SOURCE %q: must be true or false", v))
+		}
+	}
+	if v, ok := lookupEnv("LOG_DROP_KEYS"); ok {
+		var keys []string
+		for _, key := range strings.Split(v, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			opts.RecordTransformerFuncs = append(opts.RecordTransformerFuncs[:len(opts.RecordTransformerFuncs):len(opts.RecordTransformerFuncs)], DropAttrs(keys...))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	output, _ := lookupEnv("LOG_OUTPUT")
+	w, closer, err := openOutput(output, opts.Writer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_OUTPUT: %w", err)
+	}
+	opts.Writer = w
+	m := NewMiddleware(f, opts)
+	m.core.closer = closer
+	return m, nil
+}
+
+// openOutput returns the writer for "stderr", "stdout" or a file path to append to.
+// If output is empty, w is returned, or os.Stderr if w is nil. The closer is the opened file, if any.
+func openOutput(output string, w io.Writer) (io.Writer, io.Closer, error) {
+	switch output {
+	case "":
+		if w == nil {
+			w = os.Stderr
+		}
+		return w, nil, nil
+	case "stderr":
+		return os.Stderr, nil, nil
+	case "stdout":
+		return os.Stdout, nil, nil
+	}
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, file, nil
+}
+
+// lookupEnv returns the trimmed value of the environment variable key, and false if it is unset or empty.
+func lookupEnv(key string) (string, bool) {
+	v := strings.TrimSpace(os.Getenv(key))
+	return v, v != ""
+}
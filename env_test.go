@@ -0,0 +1,123 @@
+package slogutils
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setLogEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{"LOG_LEVEL", "LOG_LEVELS", "LOG_FORMAT", "LOG_COLOR", "LOG_OUTPUT", "LOG_ADD_SOURCE", "LOG_DROP_KEYS"} {
+		t.Setenv(key, env[key])
+	}
+}
+
+func TestNewMiddlewareFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	setLogEnv(t, map[string]string{
+		"LOG_LEVEL":      "warn",
+		"LOG_LEVELS":     "db=debug",
+		"LOG_FORMAT":     "json",
+		"LOG_COLOR":      "never",
+		"LOG_OUTPUT":     path,
+		"LOG_ADD_SOURCE": "true",
+		"LOG_DROP_KEYS":  "password, http.authorization",
+	})
+	m, err := NewMiddlewareFromEnv(MiddlewareOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	logger := slog.New(m)
+	ctx := With(context.Background(), "password", "secret")
+	logger.InfoContext(ctx, "dropped")
+	logger.WarnContext(ctx, "written", slog.Group("http", slog.String("authorization", "token"), slog.String("method", "GET")))
+	logger.With(LoggerKey, "db").Debug("db debug")
+	if err := m.Close(); err != nil {
+		t.Fatalf("unexpected close error: %s", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), b)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("failed to unmarshal %q: %s", lines[0], err)
+	}
+	if record["msg"] != "written" {
+		t.Errorf("unexpected msg: %v", record["msg"])
+	}
+	if _, ok := record["password"]; ok {
+		t.Errorf("expected password to be dropped: %s", lines[0])
+	}
+	if http, _ := record["http"].(map[string]any); len(http) != 1 || http["method"] != "GET" {
+		t.Errorf("expected http.authorization to be dropped: %s", lines[0])
+	}
+	if _, ok := record[slog.SourceKey]; !ok {
+		t.Errorf("expected source: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"msg":"db debug"`) {
+		t.Errorf("expected db debug, got %s", lines[1])
+	}
+}
+
+func TestNewMiddlewareFromEnv__Defaults(t *testing.T) {
+	setLogEnv(t, nil)
+	m, err := NewMiddlewareFromEnv(MiddlewareOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.core.opts.Writer != os.Stderr {
+		t.Errorf("expected stderr, got %v", m.core.opts.Writer)
+	}
+	if _, ok := m.core.h.(*slog.JSONHandler); !ok {
+		t.Errorf("expected *slog.JSONHandler, got %T", m.core.h)
+	}
+	if l := m.MinLevel(); l != slog.LevelInfo {
+		t.Errorf("expected info, got %v", l)
+	}
+
+	setLogEnv(t, map[string]string{"LOG_FORMAT": "Console", "LOG_OUTPUT": "stdout"})
+	m, err = NewMiddlewareFromEnv(MiddlewareOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := m.core.h.(*ConsoleHandler); !ok {
+		t.Errorf("expected *ConsoleHandler, got %T", m.core.h)
+	}
+	if m.core.opts.Writer != os.Stdout {
+		t.Errorf("expected stdout, got %v", m.core.opts.Writer)
+	}
+}
+
+func TestNewMiddlewareFromEnv__Invalid(t *testing.T) {
+	setLogEnv(t, map[string]string{
+		"LOG_LEVEL":      "loud",
+		"LOG_FORMAT":     "yaml",
+		"LOG_COLOR":      "rainbow",
+		"LOG_ADD_SOURCE": "maybe",
+		"LOG_LEVELS":     "db=",
+	})
+	_, err := NewMiddlewareFromEnv(MiddlewareOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, key := range []string{"LOG_LEVEL", "LOG_LEVELS", "LOG_FORMAT", "LOG_COLOR", "LOG_ADD_SOURCE"} {
+		if !strings.Contains(err.Error(), "invalid "+key) {
+			t.Errorf("expected error to name %s: %s", key, err)
+		}
+	}
+
+	setLogEnv(t, map[string]string{"LOG_OUTPUT": filepath.Join(t.TempDir(), "missing", "app.log")})
+	if _, err := NewMiddlewareFromEnv(MiddlewareOptions{}); err == nil || !strings.Contains(err.Error(), "invalid LOG_OUTPUT") {
+		t.Errorf("expected LOG_OUTPUT error, got %v", err)
+	}
+}
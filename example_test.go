@@ -5,6 +5,7 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/mashiike/slogutils"
@@ -61,3 +62,54 @@ func Example() {
 	//{"level":"WARN","msg":"this is not slog."}
 	//{"level":"ERROR","msg":"this is not slog."}
 }
+
+func ExampleNewLogLogger() {
+	middleware := slogutils.NewMiddleware(
+		slog.NewJSONHandler,
+		slogutils.MiddlewareOptions{
+			Writer: os.Stdout,
+			HandlerOptions: &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == "time" {
+						return slog.Attr{}
+					}
+					return a
+				},
+			},
+		},
+	)
+	logger := slogutils.NewLogLogger(middleware, slogutils.LogBridgeOptions{
+		LevelParsers: []slogutils.LevelParserFunc{
+			slogutils.BracketLevelParser(nil),
+			slogutils.LogfmtLevelParser(nil),
+		},
+	})
+	logger.Println("[WARN] disk is almost full")
+	logger.Println("level=error connection refused")
+	logger.Println("no level")
+
+	// Output:
+	//{"level":"WARN","msg":"disk is almost full"}
+	//{"level":"ERROR","msg":"connection refused"}
+	//{"level":"INFO","msg":"no level"}
+}
+
+func ExampleFixedTime() {
+	middleware := slogutils.NewMiddleware(
+		slog.NewJSONHandler,
+		slogutils.MiddlewareOptions{
+			RecordTransformerFuncs: []slogutils.RecordTransformerFunc{
+				slogutils.FixedTime(time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)),
+				slogutils.ZeroPC(),
+			},
+			Writer: os.Stdout,
+			HandlerOptions: &slog.HandlerOptions{
+				AddSource: true,
+			},
+		},
+	)
+	slog.New(middleware).Info("hello")
+
+	// Output:
+	//{"time":"2023-08-15T00:00:00Z","level":"INFO","msg":"hello"}
+}
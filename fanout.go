@@ -0,0 +1,85 @@
+package slogutils
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Fanout is a slog.Handler that writes each record to multiple sinks.
+// Each sink is usually a Middleware with its own handler, level, ModifierFuncs and RecordTransformerFuncs,
+// for example colored console output at Debug on stderr and JSON at Info in a file.
+// Context attributes stored by With are passed along with the context, so every sink sees them.
+type Fanout struct {
+	handlers []slog.Handler
+}
+
+// NewFanout returns a Fanout that writes to the given handlers.
+func NewFanout(handlers ...slog.Handler) *Fanout {
+	return &Fanout{handlers: handlers}
+}
+
+// Enabled implements slog.Handler. It reports whether any sink is enabled for the level.
+func (f *Fanout) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range f.handlers {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler. It writes the record to every enabled sink,
+// and returns the errors of all sinks joined.
+func (f *Fanout) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler.
+func (f *Fanout) WithAttrs(as []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(f.handlers))
+	for i, h := range f.handlers {
+		handlers[i] = h.WithAttrs(as)
+	}
+	return &Fanout{handlers: handlers}
+}
+
+// WithGroup implements slog.Handler.
+func (f *Fanout) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(f.handlers))
+	for i, h := range f.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &Fanout{handlers: handlers}
+}
+
+// Flush flushes every sink that has a Flush method, such as Middleware.
+func (f *Fanout) Flush(ctx context.Context) error {
+	var errs []error
+	for _, h := range f.handlers {
+		if fl, ok := h.(interface{ Flush(context.Context) error }); ok {
+			errs = append(errs, fl.Flush(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every sink that has a Close method, such as Middleware.
+func (f *Fanout) Close() error {
+	var errs []error
+	for _, h := range f.handlers {
+		if c, ok := h.(interface{ Close() error }); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
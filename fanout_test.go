@@ -0,0 +1,79 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestFanout(t *testing.T) {
+	console := new(bytes.Buffer)
+	file := new(bytes.Buffer)
+	removeTime := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return a
+	}
+	fanout := NewFanout(
+		NewMiddleware(
+			NewConsoleHandler,
+			MiddlewareOptions{
+				ModifierFuncs: map[slog.Level]ModifierFunc{
+					slog.LevelError: Color(color.FgRed),
+				},
+				Writer:    console,
+				ColorMode: ColorModeNever,
+				HandlerOptions: &slog.HandlerOptions{
+					Level:       slog.LevelDebug,
+					ReplaceAttr: removeTime,
+				},
+			},
+		),
+		NewMiddleware(
+			slog.NewJSONHandler,
+			MiddlewareOptions{
+				RecordTransformerFuncs: []RecordTransformerFunc{
+					DropAttrs("password"),
+				},
+				Writer: file,
+				HandlerOptions: &slog.HandlerOptions{
+					Level:       slog.LevelInfo,
+					ReplaceAttr: removeTime,
+				},
+			},
+		),
+	)
+	logger := slog.New(fanout).With("app", "foo")
+	ctx := With(context.Background(), slog.Int64("request_id", 12))
+	logger.DebugContext(ctx, "baz")
+	logger.InfoContext(ctx, "buzz", "password", "HIDDEN_VALUE")
+
+	expectedConsole := "DEBUG baz app=foo request_id=12\nINFO  buzz app=foo request_id=12 password=HIDDEN_VALUE\n"
+	if console.String() != expectedConsole {
+		t.Errorf("expected %q, got %q", expectedConsole, console.String())
+	}
+	actual := strings.Split(strings.TrimSuffix(file.String(), "\n"), "\n")
+	if len(actual) != 1 {
+		t.Fatalf("expected 1 line, got %d lines", len(actual))
+	}
+	expected := `{"level":"INFO","msg":"buzz","app":"foo","request_id":12}`
+	var actualObj, expectedObj map[string]interface{}
+	if err := json.Unmarshal([]byte(actual[0]), &actualObj); err != nil {
+		t.Fatalf("failed to unmarshal actual %q: %s", actual[0], err)
+	}
+	if err := json.Unmarshal([]byte(expected), &expectedObj); err != nil {
+		t.Fatalf("failed to unmarshal expected %q: %s", expected, err)
+	}
+	if !jsonEqual(actualObj, expectedObj) {
+		t.Errorf("expected %q, got %q", expected, actual[0])
+	}
+	if !fanout.Enabled(ctx, slog.LevelDebug) {
+		t.Errorf("expected fanout to be enabled at debug")
+	}
+}
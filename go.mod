@@ -2,10 +2,12 @@ module github.com/mashiike/slogutils
 
 go 1.21
 
-require github.com/fatih/color v1.15.0
+require (
+	github.com/fatih/color v1.15.0
+	github.com/mattn/go-isatty v0.0.19
+)
 
 require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.19 // indirect
 	golang.org/x/sys v0.11.0 // indirect
 )
@@ -0,0 +1,162 @@
+package slogutils
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestIDHeader is the default header that carries the request ID.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// HTTPOptions are options for HTTPHandler.
+type HTTPOptions struct {
+	// Logger writes the access log. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// RequestIDHeader is the header that carries the request ID. If empty, DefaultRequestIDHeader is used.
+	RequestIDHeader string
+
+	// GenerateRequestID generates a request ID when the request has none. If nil, a random 128-bit hex string is used.
+	GenerateRequestID func() string
+
+	// AccessLogLevel is the level of the access log. If nil, slog.LevelInfo is used.
+	AccessLogLevel slog.Leveler
+
+	// AccessLogMessage is the message of the access log. If empty, "http request" is used.
+	AccessLogMessage string
+}
+
+// HTTPHandler returns an http.Handler that seeds the request context by With with
+// request_id, method, path, remote_addr and user_agent, then calls next,
+// and writes an access log with status, bytes and latency when next returns.
+// If next panics, the access log is written with status 500, unless a header was already written, and the panic continues.
+//
+// The request ID is taken from the request header, or generated, and is set to the response header.
+// If the request has a valid traceparent header, it is stored in the context by WithTraceparent,
+// so that TraceparentExtractor adds trace_id and span_id.
+func HTTPHandler(next http.Handler, opts HTTPOptions) http.Handler {
+	if opts.RequestIDHeader == "" {
+		opts.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if opts.GenerateRequestID == nil {
+		opts.GenerateRequestID = generateRequestID
+	}
+	if opts.AccessLogLevel == nil {
+		opts.AccessLogLevel = slog.LevelInfo
+	}
+	if opts.AccessLogMessage == "" {
+		opts.AccessLogMessage = "http request"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get(opts.RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = opts.GenerateRequestID()
+		}
+		w.Header().Set(opts.RequestIDHeader, requestID)
+		ctx := r.Context()
+		if tp, err := ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+			ctx = WithTraceparent(ctx, tp)
+		}
+		ctx = With(ctx,
+			slog.String("request_id", requestID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
+		rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			p := recover()
+			if p != nil && !rw.wroteHeader {
+				rw.status = http.StatusInternalServerError
+			}
+			logger := opts.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			logger.LogAttrs(ctx, opts.AccessLogLevel.Level(), opts.AccessLogMessage,
+				slog.Int("status", rw.status),
+				slog.Int64("bytes", rw.bytes),
+				slog.Duration("latency", time.Since(start)),
+			)
+			if p != nil {
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
+
+func generateRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID reports whether id can be trusted as a request ID: not empty, not too long, and printable ASCII.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// responseRecorder records the status and the number of bytes written to a http.ResponseWriter.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (rw *responseRecorder) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (rw *responseRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, if the underlying http.ResponseWriter does, such as for WebSocket upgrades.
+func (rw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("slogutils: %T does not implement http.Hijacker: %w", rw.ResponseWriter, http.ErrNotSupported)
+	}
+	rw.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
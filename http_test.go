@@ -0,0 +1,152 @@
+package slogutils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			ContextExtractors: []ContextExtractorFunc{
+				TraceparentExtractor(),
+			},
+			Writer: buf,
+		},
+	))
+	h := HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "hello")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}), HTTPOptions{
+		Logger:            logger,
+		GenerateRequestID: func() string { return "generated" },
+	})
+
+	cases := []struct {
+		name      string
+		header    map[string]string
+		requestID string
+		traceID   string
+	}{
+		{"generate request id", nil, "generated", ""},
+		{"honor request id", map[string]string{"X-Request-Id": "abc-123"}, "abc-123", ""},
+		{"invalid request id", map[string]string{"X-Request-Id": "abc 123"}, "generated", ""},
+		{
+			"traceparent",
+			map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			"generated",
+			"4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodPost, "/users?x=1", nil)
+			req.Header.Set("User-Agent", "test-agent")
+			for k, v := range c.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if actual := rec.Header().Get("X-Request-Id"); actual != c.requestID {
+				t.Errorf("expected response request id %q, got %q", c.requestID, actual)
+			}
+			dec := json.NewDecoder(buf)
+			var hello, access map[string]interface{}
+			if err := dec.Decode(&hello); err != nil {
+				t.Fatal(err)
+			}
+			if err := dec.Decode(&access); err != nil {
+				t.Fatal(err)
+			}
+			for _, obj := range []map[string]interface{}{hello, access} {
+				if obj["request_id"] != c.requestID || obj["method"] != "POST" || obj["path"] != "/users" ||
+					obj["remote_addr"] != "192.0.2.1:1234" || obj["user_agent"] != "test-agent" {
+					t.Errorf("unexpected request attrs %v", obj)
+				}
+				if c.traceID != "" && obj["trace_id"] != c.traceID {
+					t.Errorf("expected trace_id %q, got %v", c.traceID, obj["trace_id"])
+				}
+			}
+			if access["msg"] != "http request" || access["status"] != float64(201) || access["bytes"] != float64(7) {
+				t.Errorf("unexpected access log %v", access)
+			}
+			if _, ok := access["latency"]; !ok {
+				t.Errorf("expected latency in access log %v", access)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler__Panic(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{Writer: buf}))
+	h := HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), HTTPOptions{Logger: logger})
+	func() {
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("expected the panic to continue, got %v", p)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	var access map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &access); err != nil {
+		t.Fatalf("expected an access log, got %q: %s", buf.String(), err)
+	}
+	if access["status"] != float64(500) {
+		t.Errorf("expected status 500, got %v", access["status"])
+	}
+}
+
+func TestHTTPHandler__Hijack(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{Writer: buf}))
+	srv := httptest.NewServer(HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("expected the response writer to implement http.Hijacker")
+			return
+		}
+		conn, rw, err := hj.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		rw.Flush()
+	}), HTTPOptions{Logger: logger}))
+	defer srv.Close()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n"))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "HTTP/1.1 101") {
+		t.Errorf("expected 101 Switching Protocols, got %q", line)
+	}
+
+	rw := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("expected http.ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package slogutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelSetter is a handler whose minimum level can be changed at runtime, such as Middleware.
+type LevelSetter interface {
+	MinLevel() slog.Level
+	SetMinLevel(slog.Leveler)
+}
+
+// LevelControlOptions are options for NewLevelControl.
+type LevelControlOptions struct {
+	// RevertAfter reverts a level change after the duration, to the level before the first pending change.
+	// If zero, changes are kept. An HTTP request can override it with the revert_after parameter.
+	RevertAfter time.Duration
+
+	// MinLevel is the lowest level that Verbose lowers to. If nil, slog.LevelDebug is used.
+	MinLevel slog.Leveler
+
+	// MaxLevel is the highest level that Quiet raises to. If nil, slog.LevelError is used.
+	MaxLevel slog.Leveler
+}
+
+// LevelControl changes the minimum level of a LevelSetter at runtime, over HTTP or by signals.
+type LevelControl struct {
+	target   LevelSetter
+	opts     LevelControlOptions
+	mu       sync.Mutex
+	base     slog.Level
+	timer    *time.Timer
+	revertAt time.Time
+}
+
+// NewLevelControl returns a new LevelControl that changes the level of target.
+func NewLevelControl(target LevelSetter, opts LevelControlOptions) *LevelControl {
+	if opts.MinLevel == nil {
+		opts.MinLevel = slog.LevelDebug
+	}
+	if opts.MaxLevel == nil {
+		opts.MaxLevel = slog.LevelError
+	}
+	return &LevelControl{target: target, opts: opts}
+}
+
+// Level returns the current minimum level.
+func (lc *LevelControl) Level() slog.Level {
+	return lc.target.MinLevel()
+}
+
+// SetLevel sets the minimum level, reverting it after LevelControlOptions.RevertAfter.
+func (lc *LevelControl) SetLevel(l slog.Level) {
+	lc.SetLevelFor(l, lc.opts.RevertAfter)
+}
+
+// SetLevelFor sets the minimum level, reverting it after d. If d is zero, the change is kept
+// and any pending revert is canceled.
+func (lc *LevelControl) SetLevelFor(l slog.Level, d time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.setLevelFor(l, d)
+}
+
+func (lc *LevelControl) setLevelFor(l slog.Level, d time.Duration) {
+	if lc.timer != nil {
+		lc.timer.Stop()
+		lc.timer = nil
+		lc.revertAt = time.Time{}
+	} else {
+		lc.base = lc.target.MinLevel()
+	}
+	lc.target.SetMinLevel(l)
+	if d <= 0 {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+		if lc.timer != timer {
+			return
+		}
+		lc.target.SetMinLevel(lc.base)
+		lc.timer = nil
+		lc.revertAt = time.Time{}
+	})
+	lc.timer = timer
+	lc.revertAt = time.Now().Add(d)
+}
+
+// Verbose lowers the minimum level by one step of 4, such as from slog.LevelInfo to slog.LevelDebug,
+// but not below LevelControlOptions.MinLevel. A level already below MinLevel is left unchanged.
+func (lc *LevelControl) Verbose() {
+	lc.step(-4)
+}
+
+// Quiet raises the minimum level by one step of 4, such as from slog.LevelInfo to slog.LevelWarn,
+// but not above LevelControlOptions.MaxLevel. A level already above MaxLevel is left unchanged.
+func (lc *LevelControl) Quiet() {
+	lc.step(4)
+}
+
+func (lc *LevelControl) step(delta slog.Level) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	current := lc.target.MinLevel()
+	l := current + delta
+	// clamp only in the direction of the step, so that a level set beyond the bounds by SetLevel is not stepped back.
+	if lo := lc.opts.MinLevel.Level(); delta < 0 && l < lo {
+		l = lo
+	}
+	if hi := lc.opts.MaxLevel.Level(); delta > 0 && l > hi {
+		l = hi
+	}
+	if (delta < 0 && l > current) || (delta > 0 && l < current) {
+		return
+	}
+	lc.setLevelFor(l, lc.opts.RevertAfter)
+}
+
+// ListenSignals calls Verbose when the process receives the verbose signal and Quiet when it receives the quiet signal.
+// Call the returned function to stop listening.
+func (lc *LevelControl) ListenSignals(verbose, quiet os.Signal) (stop func()) {
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, verbose, quiet)
+	go func() {
+		for {
+			select {
+			case s := <-sig:
+				if s == verbose {
+					lc.Verbose()
+				} else {
+					lc.Quiet()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sig)
+			close(done)
+		})
+	}
+}
+
+type levelControlBody struct {
+	Level       string `json:"level"`
+	RevertAfter string `json:"revert_after,omitempty"`
+	RevertAt    string `json:"revert_at,omitempty"`
+}
+
+// ServeHTTP implements http.Handler.
+//
+// GET returns the current level. PUT and POST set it from a plain text body such as "debug",
+// or from a JSON body such as {"level":"debug","revert_after":"10m"}, and return the new level.
+// The revert_after query parameter also sets the revert duration; "0" keeps the change.
+// The response is JSON when the request accepts application/json or has the query parameter format=json,
+// and plain text otherwise.
+func (lc *LevelControl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		if err := lc.update(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	lc.mu.Lock()
+	body := levelControlBody{Level: lc.target.MinLevel().String()}
+	if !lc.revertAt.IsZero() {
+		body.RevertAt = lc.revertAt.Format(time.RFC3339)
+	}
+	lc.mu.Unlock()
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, body.Level)
+}
+
+func (lc *LevelControl) update(r *http.Request) error {
+	b, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+	if err != nil {
+		return err
+	}
+	var body levelControlBody
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.Unmarshal(b, &body); err != nil {
+			return fmt.Errorf("invalid JSON body: %w", err)
+		}
+	} else {
+		body.Level = strings.TrimSpace(string(b))
+	}
+	if body.Level == "" {
+		body.Level = r.URL.Query().Get("level")
+	}
+	if body.Level == "" {
+		return fmt.Errorf("level is required")
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(body.Level)); err != nil {
+		return err
+	}
+	d := lc.opts.RevertAfter
+	if s := r.URL.Query().Get("revert_after"); s != "" {
+		body.RevertAfter = s
+	}
+	if body.RevertAfter != "" {
+		if d, err = time.ParseDuration(body.RevertAfter); err != nil {
+			return fmt.Errorf("invalid revert_after: %w", err)
+		}
+	}
+	lc.SetLevelFor(l, d)
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package slogutils
+
+// ListenUserSignals does nothing on platforms without SIGUSR1 and SIGUSR2.
+func (lc *LevelControl) ListenUserSignals() (stop func()) {
+	return func() {}
+}
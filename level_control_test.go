@@ -0,0 +1,122 @@
+package slogutils
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelControl__HTTP(t *testing.T) {
+	middleware := NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{Writer: new(bytes.Buffer)})
+	lc := NewLevelControl(middleware, LevelControlOptions{})
+	cases := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		accept      string
+		body        string
+
+		expectedStatus int
+		expectedBody   string
+		expectedLevel  slog.Level
+	}{
+		{"get text", http.MethodGet, "/", "", "", "", http.StatusOK, "INFO\n", slog.LevelInfo},
+		{"get json", http.MethodGet, "/", "", "application/json", "", http.StatusOK, `{"level":"INFO"}` + "\n", slog.LevelInfo},
+		{"put text", http.MethodPut, "/", "text/plain", "", "debug\n", http.StatusOK, "DEBUG\n", slog.LevelDebug},
+		{"put json", http.MethodPut, "/?format=json", "application/json", "", `{"level":"warn"}`, http.StatusOK, `{"level":"WARN"}` + "\n", slog.LevelWarn},
+		{"post query", http.MethodPost, "/?level=error", "", "", "", http.StatusOK, "ERROR\n", slog.LevelError},
+		{"put offset", http.MethodPut, "/", "", "", "info+2", http.StatusOK, "INFO+2\n", slog.LevelInfo + 2},
+		{"invalid level", http.MethodPut, "/", "", "", "verbose", http.StatusBadRequest, "", slog.LevelInfo + 2},
+		{"invalid revert_after", http.MethodPut, "/?revert_after=soon", "", "", "debug", http.StatusBadRequest, "", slog.LevelInfo + 2},
+		{"missing level", http.MethodPut, "/", "", "", "", http.StatusBadRequest, "", slog.LevelInfo + 2},
+		{"method not allowed", http.MethodDelete, "/", "", "", "", http.StatusMethodNotAllowed, "", slog.LevelInfo + 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+			if c.contentType != "" {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			if c.accept != "" {
+				req.Header.Set("Accept", c.accept)
+			}
+			rec := httptest.NewRecorder()
+			lc.ServeHTTP(rec, req)
+			if rec.Code != c.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", c.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if c.expectedBody != "" && rec.Body.String() != c.expectedBody {
+				t.Errorf("expected body %q, got %q", c.expectedBody, rec.Body.String())
+			}
+			if level := middleware.MinLevel(); level != c.expectedLevel {
+				t.Errorf("expected level %v, got %v", c.expectedLevel, level)
+			}
+		})
+	}
+}
+
+func TestLevelControl__RevertAfter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{Writer: buf})
+	lc := NewLevelControl(middleware, LevelControlOptions{RevertAfter: 50 * time.Millisecond})
+	lc.SetLevel(slog.LevelDebug)
+	lc.SetLevel(slog.LevelDebug - 4)
+	if level := lc.Level(); level != slog.LevelDebug-4 {
+		t.Fatalf("expected level %v, got %v", slog.LevelDebug-4, level)
+	}
+	rec := httptest.NewRecorder()
+	lc.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=json", nil))
+	if !strings.Contains(rec.Body.String(), `"revert_at"`) {
+		t.Errorf("expected revert_at in %s", rec.Body.String())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for lc.Level() != slog.LevelInfo {
+		if time.Now().After(deadline) {
+			t.Fatalf("level was not reverted: %v", lc.Level())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	lc.SetLevelFor(slog.LevelWarn, 0)
+	time.Sleep(100 * time.Millisecond)
+	if level := lc.Level(); level != slog.LevelWarn {
+		t.Errorf("expected level %v to be kept, got %v", slog.LevelWarn, level)
+	}
+}
+
+func TestLevelControl__VerboseQuiet(t *testing.T) {
+	middleware := NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{Writer: new(bytes.Buffer)})
+	lc := NewLevelControl(middleware, LevelControlOptions{})
+	expected := []slog.Level{slog.LevelDebug, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError, slog.LevelError}
+	ops := []func(){lc.Verbose, lc.Verbose, lc.Quiet, lc.Quiet, lc.Quiet, lc.Quiet}
+	for i, op := range ops {
+		op()
+		if level := lc.Level(); level != expected[i] {
+			t.Errorf("step %d: expected level %v, got %v", i, expected[i], level)
+		}
+	}
+}
+
+func TestLevelControl__VerboseQuietOutOfBounds(t *testing.T) {
+	middleware := NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{Writer: new(bytes.Buffer)})
+	lc := NewLevelControl(middleware, LevelControlOptions{})
+	lc.SetLevel(slog.LevelDebug - 4)
+	lc.Verbose()
+	if level := lc.Level(); level != slog.LevelDebug-4 {
+		t.Errorf("expected Verbose to keep level %v, got %v", slog.LevelDebug-4, level)
+	}
+	lc.SetLevel(slog.LevelError + 4)
+	lc.Quiet()
+	if level := lc.Level(); level != slog.LevelError+4 {
+		t.Errorf("expected Quiet to keep level %v, got %v", slog.LevelError+4, level)
+	}
+	lc.Verbose()
+	if level := lc.Level(); level != slog.LevelError {
+		t.Errorf("expected Verbose to step to %v, got %v", slog.LevelError, level)
+	}
+}
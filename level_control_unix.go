@@ -0,0 +1,11 @@
+//go:build unix
+
+package slogutils
+
+import "syscall"
+
+// ListenUserSignals calls Verbose on SIGUSR1 and Quiet on SIGUSR2.
+// Call the returned function to stop listening.
+func (lc *LevelControl) ListenUserSignals() (stop func()) {
+	return lc.ListenSignals(syscall.SIGUSR1, syscall.SIGUSR2)
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package slogutils
+
+import (
+	"bytes"
+	"log/slog"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLevelControl__ListenUserSignals(t *testing.T) {
+	middleware := NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{Writer: new(bytes.Buffer)})
+	lc := NewLevelControl(middleware, LevelControlOptions{})
+	stop := lc.ListenUserSignals()
+	defer stop()
+
+	waitLevel := func(expected slog.Level) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for lc.Level() != expected {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected level %v, got %v", expected, lc.Level())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitLevel(slog.LevelDebug)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitLevel(slog.LevelInfo)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitLevel(slog.LevelWarn)
+}
@@ -0,0 +1,227 @@
+package slogutils
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultLegacyLevels maps the level names commonly written by legacy loggers to slog.Level.
+var DefaultLegacyLevels = map[string]slog.Level{
+	"DEBUG":   slog.LevelDebug,
+	"INFO":    slog.LevelInfo,
+	"WARN":    slog.LevelWarn,
+	"WARNING": slog.LevelWarn,
+	"ERROR":   slog.LevelError,
+}
+
+// LevelParserFunc parses a legacy level out of a line written to the standard log package.
+// It returns the level and the rest of the line, or ok false if the line has no level it recognizes.
+type LevelParserFunc func(line string) (level slog.Level, rest string, ok bool)
+
+// BracketLevelParser returns a LevelParserFunc for lines such as "[WARN] message".
+// The level names are looked up in levelMap case-insensitively. If levelMap is nil, DefaultLegacyLevels is used.
+func BracketLevelParser(levelMap map[string]slog.Level) LevelParserFunc {
+	levels := upperLevelMap(levelMap)
+	return func(line string) (slog.Level, string, bool) {
+		s := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(s, "[") {
+			return 0, line, false
+		}
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return 0, line, false
+		}
+		level, ok := levels[strings.ToUpper(s[1:end])]
+		if !ok {
+			return 0, line, false
+		}
+		return level, strings.TrimSpace(s[end+1:]), true
+	}
+}
+
+// ColonLevelParser returns a LevelParserFunc for lines such as "WARN: message".
+// The level names are looked up in levelMap case-insensitively. If levelMap is nil, DefaultLegacyLevels is used.
+func ColonLevelParser(levelMap map[string]slog.Level) LevelParserFunc {
+	levels := upperLevelMap(levelMap)
+	return func(line string) (slog.Level, string, bool) {
+		s := strings.TrimLeft(line, " ")
+		end := strings.IndexByte(s, ':')
+		if end < 0 || strings.ContainsRune(s[:end], ' ') {
+			return 0, line, false
+		}
+		level, ok := levels[strings.ToUpper(s[:end])]
+		if !ok {
+			return 0, line, false
+		}
+		return level, strings.TrimSpace(s[end+1:]), true
+	}
+}
+
+// LogfmtLevelParser returns a LevelParserFunc for lines that contain a "level=warn" or "lvl=warn" pair anywhere.
+// The line is parsed like ParseLogfmt does, so the value may be quoted, and the pair is removed from the rest of the line.
+// The level names are looked up in levelMap case-insensitively. If levelMap is nil, DefaultLegacyLevels is used.
+func LogfmtLevelParser(levelMap map[string]slog.Level) LevelParserFunc {
+	levels := upperLevelMap(levelMap)
+	return func(line string) (slog.Level, string, bool) {
+		for _, t := range parseLogfmt(line) {
+			if t.key != "level" && t.key != "lvl" {
+				continue
+			}
+			level, ok := levels[strings.ToUpper(t.value)]
+			if !ok {
+				return 0, line, false
+			}
+			rest := strings.TrimSpace(strings.TrimSpace(line[:t.start]) + " " + strings.TrimSpace(line[t.end:]))
+			return level, rest, true
+		}
+		return 0, line, false
+	}
+}
+
+// GlogLevelParser returns a LevelParserFunc for lines in the glog format, such as
+// "W0102 15:04:05.000000 12345 main.go:10] message".
+// I, W, E and F are converted to slog.LevelInfo, slog.LevelWarn, slog.LevelError and slog.LevelError+4,
+// and the header is removed from the rest of the line.
+func GlogLevelParser() LevelParserFunc {
+	return func(line string) (slog.Level, string, bool) {
+		if len(line) < 5 {
+			return 0, line, false
+		}
+		var level slog.Level
+		switch line[0] {
+		case 'I':
+			level = slog.LevelInfo
+		case 'W':
+			level = slog.LevelWarn
+		case 'E':
+			level = slog.LevelError
+		case 'F':
+			level = slog.LevelError + 4
+		default:
+			return 0, line, false
+		}
+		for i := 1; i < 5; i++ {
+			if line[i] < '0' || line[i] > '9' {
+				return 0, line, false
+			}
+		}
+		end := strings.Index(line, "] ")
+		if end < 0 {
+			return 0, line, false
+		}
+		return level, strings.TrimSpace(line[end+2:]), true
+	}
+}
+
+func upperLevelMap(levelMap map[string]slog.Level) map[string]slog.Level {
+	if levelMap == nil {
+		levelMap = DefaultLegacyLevels
+	}
+	levels := make(map[string]slog.Level, len(levelMap))
+	for k, v := range levelMap {
+		levels[strings.ToUpper(k)] = v
+	}
+	return levels
+}
+
+// LogBridgeOptions are options for NewLogWriter and NewLogLogger.
+type LogBridgeOptions struct {
+	// Level is the level of lines that no LevelParsers recognize. If nil, slog.LevelInfo is used.
+	Level slog.Leveler
+
+	// LevelParsers are tried in order to parse the level of each line.
+	// If nil, BracketLevelParser(nil) is used. Set it to an empty slice to disable parsing.
+	LevelParsers []LevelParserFunc
+
+	// SplitLines writes each line of a multi-line message as its own record.
+	// Lines that no LevelParsers recognize take the level of the first line.
+	// If false, a multi-line message is written as one record, and its level is parsed from the first line.
+	SplitLines bool
+}
+
+type logWriter struct {
+	h    slog.Handler
+	opts LogBridgeOptions
+}
+
+// NewLogWriter returns an io.Writer that writes each message to h as a slog.Record,
+// for use with log.SetOutput or any library that takes an io.Writer for its logs.
+// The record's PC is the caller of the log package, so that HandlerOptions.AddSource reports the right line.
+//
+// The log.Logger that writes to it should have no flags, because a date prefix hides the legacy level:
+//
+//	log.SetFlags(0)
+//	log.SetOutput(slogutils.NewLogWriter(middleware, slogutils.LogBridgeOptions{}))
+func NewLogWriter(h slog.Handler, opts LogBridgeOptions) io.Writer {
+	if opts.Level == nil {
+		opts.Level = slog.LevelInfo
+	}
+	if opts.LevelParsers == nil {
+		opts.LevelParsers = []LevelParserFunc{BracketLevelParser(nil)}
+	}
+	return &logWriter{h: h, opts: opts}
+}
+
+// NewLogLogger returns a *log.Logger that writes to h through NewLogWriter.
+func NewLogLogger(h slog.Handler, opts LogBridgeOptions) *log.Logger {
+	return log.New(NewLogWriter(h, opts), "", 0)
+}
+
+// Write implements io.Writer.
+func (w *logWriter) Write(b []byte) (int, error) {
+	ctx := context.Background()
+	now := time.Now()
+	pc := callerPC()
+	msg := strings.TrimSuffix(string(b), "\n")
+	if !w.opts.SplitLines {
+		level, rest := w.parseLevel(msg, w.opts.Level.Level())
+		return len(b), w.handle(ctx, now, level, rest, pc)
+	}
+	lines := strings.Split(msg, "\n")
+	first, _ := w.parseLevel(lines[0], w.opts.Level.Level())
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		level, rest := w.parseLevel(line, first)
+		if err := w.handle(ctx, now, level, rest, pc); err != nil {
+			return len(b), err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *logWriter) parseLevel(line string, def slog.Level) (slog.Level, string) {
+	for _, p := range w.opts.LevelParsers {
+		if level, rest, ok := p(line); ok {
+			return level, rest
+		}
+	}
+	return def, line
+}
+
+func (w *logWriter) handle(ctx context.Context, t time.Time, level slog.Level, msg string, pc uintptr) error {
+	if !w.h.Enabled(ctx, level) {
+		return nil
+	}
+	return w.h.Handle(ctx, slog.NewRecord(t, level, msg, pc))
+}
+
+// callerPC returns the PC of the first caller of logWriter.Write outside of the log package.
+func callerPC() uintptr {
+	var pcs [16]uintptr
+	// skip runtime.Callers, callerPC and logWriter.Write.
+	n := runtime.Callers(3, pcs[:])
+	for i := 0; i < n; i++ {
+		frame, _ := runtime.CallersFrames(pcs[i : i+1]).Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			return pcs[i]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package slogutils
+
+import (
+	"bytes"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLevelParsers(t *testing.T) {
+	cases := []struct {
+		name   string
+		parser LevelParserFunc
+		line   string
+		level  slog.Level
+		rest   string
+		ok     bool
+	}{
+		{"bracket", BracketLevelParser(nil), "[WARN] disk is full", slog.LevelWarn, "disk is full", true},
+		{"bracket lower", BracketLevelParser(nil), "[debug] hello", slog.LevelDebug, "hello", true},
+		{"bracket unknown", BracketLevelParser(nil), "[db] hello", 0, "[db] hello", false},
+		{"bracket custom", BracketLevelParser(map[string]slog.Level{"trace": slog.LevelDebug - 4}), "[TRACE] hello", slog.LevelDebug - 4, "hello", true},
+		{"colon", ColonLevelParser(nil), "ERROR: connection refused", slog.LevelError, "connection refused", true},
+		{"colon sentence", ColonLevelParser(nil), "note that: error happened", 0, "note that: error happened", false},
+		{"logfmt", LogfmtLevelParser(nil), "level=warn msg=retrying attempt=2", slog.LevelWarn, "msg=retrying attempt=2", true},
+		{"logfmt middle", LogfmtLevelParser(nil), `ts=1 lvl="error" boom`, slog.LevelError, "ts=1 boom", true},
+		{"logfmt none", LogfmtLevelParser(nil), "levels=3 ok", 0, "levels=3 ok", false},
+		{"logfmt quoted", LogfmtLevelParser(nil), `err="level=error in text" level=info done`, slog.LevelInfo, `err="level=error in text" done`, true},
+		{"glog", GlogLevelParser(), "W0102 15:04:05.000000 12345 main.go:10] slow query", slog.LevelWarn, "slow query", true},
+		{"glog fatal", GlogLevelParser(), "F0102 15:04:05.000000 12345 main.go:10] crash", slog.LevelError + 4, "crash", true},
+		{"glog plain", GlogLevelParser(), "Info from somewhere] x", 0, "Info from somewhere] x", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			level, rest, ok := c.parser(c.line)
+			if ok != c.ok {
+				t.Fatalf("expected ok %v, got %v", c.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if level != c.level {
+				t.Errorf("expected level %v, got %v", c.level, level)
+			}
+			if rest != c.rest {
+				t.Errorf("expected rest %q, got %q", c.rest, rest)
+			}
+		})
+	}
+}
+
+func TestNewLogLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		AddSource: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.Attr{}
+			case slog.SourceKey:
+				src := a.Value.Any().(*slog.Source)
+				return slog.String(slog.SourceKey, src.Function)
+			}
+			return a
+		},
+	})
+	logger := NewLogLogger(h, LogBridgeOptions{
+		LevelParsers: []LevelParserFunc{BracketLevelParser(nil), GlogLevelParser()},
+	})
+	logger.Println("[WARN] first")
+	logger.Print("E0102 15:04:05.000000 1 main.go:10] second")
+	logger.Print("third")
+	logger.Print("[DEBUG] dropped")
+
+	pc, _, _, _ := runtime.Caller(0)
+	function := runtime.FuncForPC(pc).Name()
+	expected := strings.Join([]string{
+		"level=WARN source=" + function + " msg=first",
+		"level=ERROR source=" + function + " msg=second",
+		"level=INFO source=" + function + " msg=third",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestNewLogWriter__MultiLine(t *testing.T) {
+	removeTime := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	t.Run("single record", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w := NewLogWriter(slog.NewTextHandler(buf, &slog.HandlerOptions{ReplaceAttr: removeTime}), LogBridgeOptions{})
+		w.Write([]byte("[ERROR] panic: boom\ngoroutine 1\n"))
+		expected := "level=ERROR msg=\"panic: boom\\ngoroutine 1\"\n"
+		if buf.String() != expected {
+			t.Errorf("expected %q, got %q", expected, buf.String())
+		}
+	})
+	t.Run("split lines", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w := NewLogWriter(slog.NewTextHandler(buf, &slog.HandlerOptions{ReplaceAttr: removeTime}), LogBridgeOptions{
+			SplitLines: true,
+		})
+		w.Write([]byte("[ERROR] panic: boom\ngoroutine 1\n\n[INFO] recovered\n"))
+		expected := strings.Join([]string{
+			`level=ERROR msg="panic: boom"`,
+			`level=ERROR msg="goroutine 1"`,
+			`level=INFO msg=recovered`,
+			"",
+		}, "\n")
+		if buf.String() != expected {
+			t.Errorf("expected %q, got %q", expected, buf.String())
+		}
+	})
+}
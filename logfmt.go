@@ -0,0 +1,137 @@
+package slogutils
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// LogfmtOptions are options for ParseLogfmt.
+type LogfmtOptions struct {
+	// MessageKey is the key of the pair whose value becomes the message when no text is left after parsing.
+	// If empty, "msg" is used.
+	MessageKey string
+
+	// LevelMap converts the value of the first "level" or "lvl" pair to the level of a slog.LevelInfo record, case-insensitively.
+	// The pair is removed only if it sets the level; otherwise, and if LevelMap is nil, it is kept as an attribute.
+	LevelMap map[string]slog.Level
+}
+
+// ParseLogfmt returns a RecordTransformerFunc that parses key=value and key="quoted value" pairs
+// out of the message into attributes, leaving the rest of the text as the message.
+// Parsed values are strings. Quoted values are unquoted as Go string literals.
+// If the message has no pairs, the slog.Record is not changed.
+//
+// Example:
+//
+//	ParseLogfmt(LogfmtOptions{LevelMap: DefaultLegacyLevels})
+//	If the message is `level=warn retrying request id=12 err="connection refused"`,
+//	the slog.Record becomes a slog.LevelWarn record with the message "retrying request"
+//	and the attributes id=12 and err="connection refused".
+func ParseLogfmt(opts LogfmtOptions) RecordTransformerFunc {
+	if opts.MessageKey == "" {
+		opts.MessageKey = "msg"
+	}
+	var levels map[string]slog.Level
+	if opts.LevelMap != nil {
+		levels = upperLevelMap(opts.LevelMap)
+	}
+	return func(r slog.Record) slog.Record {
+		if !strings.Contains(r.Message, "=") {
+			return r
+		}
+		var text []string
+		var pairs []slog.Attr
+		for _, t := range parseLogfmt(r.Message) {
+			if t.key == "" {
+				text = append(text, r.Message[t.start:t.end])
+				continue
+			}
+			pairs = append(pairs, slog.String(t.key, t.value))
+		}
+		if len(pairs) == 0 {
+			return r
+		}
+		c := r.Clone()
+		c.Message = strings.Join(text, " ")
+		attrs := make([]slog.Attr, 0, len(pairs))
+		levelSet := false
+		for _, a := range pairs {
+			if levels != nil && !levelSet && r.Level == slog.LevelInfo && (a.Key == "level" || a.Key == "lvl") {
+				if level, ok := levels[strings.ToUpper(a.Value.String())]; ok {
+					c.Level = level
+					levelSet = true
+					continue
+				}
+			}
+			if a.Key == opts.MessageKey && c.Message == "" {
+				c.Message = a.Value.String()
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		c.AddAttrs(attrs...)
+		return c
+	}
+}
+
+// logfmtToken is a key=value pair, or a word of text if key is empty, at s[start:end] of the parsed string s.
+type logfmtToken struct {
+	start, end int
+	key, value string
+}
+
+// parseLogfmt splits s at spaces into key=value pairs and words of text.
+func parseLogfmt(s string) []logfmtToken {
+	var tokens []logfmtToken
+	for i := 0; i < len(s); {
+		if s[i] == ' ' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != '=' && s[i] != '"' {
+			i++
+		}
+		if i > start && i < len(s) && s[i] == '=' {
+			key := s[start:i]
+			if value, n, ok := parseLogfmtValue(s[i+1:]); ok {
+				i += 1 + n
+				tokens = append(tokens, logfmtToken{start: start, end: i, key: key, value: value})
+				continue
+			}
+		}
+		for i < len(s) && s[i] != ' ' {
+			i++
+		}
+		tokens = append(tokens, logfmtToken{start: start, end: i})
+	}
+	return tokens
+}
+
+// parseLogfmtValue parses the value at the start of s, and returns it and the number of bytes it takes.
+func parseLogfmtValue(s string) (string, int, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		n := strings.IndexByte(s, ' ')
+		if n < 0 {
+			n = len(s)
+		}
+		return s[:n], n, true
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			if i+1 < len(s) && s[i+1] != ' ' {
+				return "", 0, false
+			}
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, false
+			}
+			return value, i + 1, true
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,106 @@
+package slogutils
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    LogfmtOptions
+		level   slog.Level
+		message string
+
+		expectedLevel   slog.Level
+		expectedMessage string
+		expectedAttrs   map[string]string
+	}{
+		{
+			name:            "pairs and text",
+			message:         `retrying request id=12 err="connection refused: \"db\"" attempt=2`,
+			expectedMessage: "retrying request",
+			expectedAttrs:   map[string]string{"id": "12", "err": `connection refused: "db"`, "attempt": "2"},
+		},
+		{
+			name:            "no pairs",
+			message:         "a = b and =y",
+			expectedMessage: "a = b and =y",
+			expectedAttrs:   map[string]string{},
+		},
+		{
+			name:            "unterminated quote is text",
+			message:         `done key="oops`,
+			expectedMessage: `done key="oops`,
+			expectedAttrs:   map[string]string{},
+		},
+		{
+			name:            "empty value",
+			message:         "start user= ok",
+			expectedMessage: "start ok",
+			expectedAttrs:   map[string]string{"user": ""},
+		},
+		{
+			name:            "message key",
+			message:         `msg="request done" status=200`,
+			expectedMessage: "request done",
+			expectedAttrs:   map[string]string{"status": "200"},
+		},
+		{
+			name:            "level kept without level map",
+			message:         "level=warn slow query",
+			expectedLevel:   slog.LevelInfo,
+			expectedMessage: "slow query",
+			expectedAttrs:   map[string]string{"level": "warn"},
+		},
+		{
+			name:            "level converted",
+			opts:            LogfmtOptions{LevelMap: DefaultLegacyLevels},
+			message:         "level=warn slow query",
+			expectedLevel:   slog.LevelWarn,
+			expectedMessage: "slow query",
+			expectedAttrs:   map[string]string{},
+		},
+		{
+			name:            "level not overridden",
+			opts:            LogfmtOptions{LevelMap: DefaultLegacyLevels},
+			level:           slog.LevelError,
+			message:         "lvl=debug boom",
+			expectedLevel:   slog.LevelError,
+			expectedMessage: "boom",
+			expectedAttrs:   map[string]string{"lvl": "debug"},
+		},
+		{
+			name:            "only the first level converted",
+			opts:            LogfmtOptions{LevelMap: DefaultLegacyLevels},
+			message:         "level=warn retry level=error",
+			expectedLevel:   slog.LevelWarn,
+			expectedMessage: "retry",
+			expectedAttrs:   map[string]string{"level": "error"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := slog.NewRecord(time.Now(), c.level, c.message, 0)
+			r.AddAttrs(slog.String("existing", "1"))
+			actual := ParseLogfmt(c.opts)(r)
+			if actual.Level != c.expectedLevel {
+				t.Errorf("expected level %v, got %v", c.expectedLevel, actual.Level)
+			}
+			if actual.Message != c.expectedMessage {
+				t.Errorf("expected message %q, got %q", c.expectedMessage, actual.Message)
+			}
+			c.expectedAttrs["existing"] = "1"
+			attrs := flattenRecordAttrs(actual)
+			if len(attrs) != len(c.expectedAttrs) {
+				t.Errorf("expected attrs %v, got %v", c.expectedAttrs, attrs)
+			}
+			for k, v := range c.expectedAttrs {
+				if attrs[k] != v {
+					t.Errorf("expected %s=%q, got %q", k, v, attrs[k])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package slogutils
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LoggerKey is the key of the attribute that names the logger of a record, as in logger.With(LoggerKey, "db").
+const LoggerKey = "logger"
+
+// LoggerLevels holds minimum levels per logger name, for MiddlewareOptions.LoggerLevels.
+//
+// The name of a record's logger is the string value of its LoggerKey attribute, the innermost one if it is also inside groups,
+// or the import path of the package that logged it if it has none.
+// Names are hierarchical: "http.client.pool" uses the level of "http.client.pool", "http.client" or "http",
+// whichever is configured first. Names with a slash, such as "github.com/acme/app/db", are split at slashes only,
+// so they use "github.com/acme/app/db", "github.com/acme/app", "github.com/acme" or "github.com".
+// "*" is the level of the records whose logger matches no name.
+//
+// LoggerLevels implements flag.Value, and can be changed at runtime by Set and SetLevel.
+type LoggerLevels struct {
+	mu       sync.RWMutex
+	levels   map[string]slog.Level
+	min      slog.Level
+	packages sync.Map // PC to package path
+}
+
+// ParseLoggerLevels parses a spec such as "db=debug,http.client=warn,*=info".
+// A level without a name, such as "info", is the same as "*=info".
+// Levels are parsed by slog.Level.UnmarshalText, so "debug", "WARN" and "info+2" are valid.
+func ParseLoggerLevels(spec string) (*LoggerLevels, error) {
+	ll := &LoggerLevels{}
+	if err := ll.Set(spec); err != nil {
+		return nil, err
+	}
+	return ll, nil
+}
+
+// Set replaces all levels with the levels parsed from spec, in the format of ParseLoggerLevels.
+func (ll *LoggerLevels) Set(spec string) error {
+	levels := make(map[string]slog.Level)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, level, found := strings.Cut(entry, "=")
+		if !found {
+			name, level = "*", name
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("slogutils: invalid logger level %q: empty logger name", entry)
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+			return fmt.Errorf("slogutils: invalid logger level %q: %w", entry, err)
+		}
+		levels[name] = l
+	}
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	ll.levels = levels
+	ll.updateMin()
+	return nil
+}
+
+// SetLevel sets the level of a logger name.
+func (ll *LoggerLevels) SetLevel(name string, l slog.Level) {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	if ll.levels == nil {
+		ll.levels = make(map[string]slog.Level)
+	}
+	ll.levels[name] = l
+	ll.updateMin()
+}
+
+// Level returns the level of the logger name, and false if neither the name, its parents nor "*" are configured.
+func (ll *LoggerLevels) Level(name string) (slog.Level, bool) {
+	ll.mu.RLock()
+	defer ll.mu.RUnlock()
+	sep := "."
+	if strings.Contains(name, "/") {
+		sep = "/"
+	}
+	for name != "" {
+		if l, ok := ll.levels[name]; ok {
+			return l, true
+		}
+		i := strings.LastIndex(name, sep)
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	l, ok := ll.levels["*"]
+	return l, ok
+}
+
+// String returns the levels in the format of ParseLoggerLevels, sorted by name.
+func (ll *LoggerLevels) String() string {
+	if ll == nil {
+		return ""
+	}
+	ll.mu.RLock()
+	defer ll.mu.RUnlock()
+	names := make([]string, 0, len(ll.levels))
+	for name := range ll.levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entries[i] = name + "=" + ll.levels[name].String()
+	}
+	return strings.Join(entries, ",")
+}
+
+// minLevel returns the lowest configured level, and false if no level is configured.
+func (ll *LoggerLevels) minLevel() (slog.Level, bool) {
+	ll.mu.RLock()
+	defer ll.mu.RUnlock()
+	return ll.min, len(ll.levels) > 0
+}
+
+func (ll *LoggerLevels) updateMin() {
+	first := true
+	for _, l := range ll.levels {
+		if first || l < ll.min {
+			ll.min = l
+			first = false
+		}
+	}
+}
+
+// recordLevel returns the level of the logger of r.
+func (ll *LoggerLevels) recordLevel(r slog.Record) (slog.Level, bool) {
+	name := loggerName(recordAttrs(r))
+	if name == "" {
+		name = ll.packagePath(r.PC)
+	}
+	return ll.Level(name)
+}
+
+// loggerName returns the value of the last LoggerKey attribute in attrs and their groups,
+// which is the innermost one for the attributes added by WithAttrs and WithGroup.
+func loggerName(attrs []slog.Attr) string {
+	name := ""
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			if n := loggerName(v.Group()); n != "" {
+				name = n
+			}
+			continue
+		}
+		if a.Key == LoggerKey {
+			name = v.String()
+		}
+	}
+	return name
+}
+
+// packagePath returns the import path of the package of the function at pc.
+func (ll *LoggerLevels) packagePath(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	if p, ok := ll.packages.Load(pc); ok {
+		return p.(string)
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	p := functionPackage(frame.Function)
+	ll.packages.Store(pc, p)
+	return p
+}
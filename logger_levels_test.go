@@ -0,0 +1,124 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLoggerLevels(t *testing.T) {
+	ll, err := ParseLoggerLevels(" db=debug, http.client=warn ,*=info,github.com/acme=error+2,")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := ll.String(); s != "*=INFO,db=DEBUG,github.com/acme=ERROR+2,http.client=WARN" {
+		t.Errorf("unexpected String: %s", s)
+	}
+	cases := []struct {
+		name     string
+		expected slog.Level
+	}{
+		{"db", slog.LevelDebug},
+		{"db.pool", slog.LevelDebug},
+		{"dbx", slog.LevelInfo},
+		{"http", slog.LevelInfo},
+		{"http.client", slog.LevelWarn},
+		{"http.client.transport", slog.LevelWarn},
+		{"github.com/acme/app/db", slog.LevelError + 2},
+		{"github.com/acme.v2/db", slog.LevelInfo},
+		{"", slog.LevelInfo},
+	}
+	for _, c := range cases {
+		if l, ok := ll.Level(c.name); !ok || l != c.expected {
+			t.Errorf("%q: expected %v, got %v (ok=%v)", c.name, c.expected, l, ok)
+		}
+	}
+
+	ll, err = ParseLoggerLevels("debug")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if l, ok := ll.Level("anything"); !ok || l != slog.LevelDebug {
+		t.Errorf("expected bare level to be the default, got %v (ok=%v)", l, ok)
+	}
+	ll, _ = ParseLoggerLevels("db=debug")
+	if _, ok := ll.Level("http"); ok {
+		t.Error("expected no level without *")
+	}
+	ll, _ = ParseLoggerLevels("github=debug,github.com=warn")
+	if l, ok := ll.Level("github.com/acme/app"); !ok || l != slog.LevelWarn {
+		t.Errorf("expected the level of github.com, got %v (ok=%v)", l, ok)
+	}
+
+	for _, spec := range []string{"db=verbose", "=debug", "db=debug,http"} {
+		if _, err := ParseLoggerLevels(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestLoggerLevels__DottedPackage(t *testing.T) {
+	ll, err := ParseLoggerLevels("gopkg.in/yaml.v3=warn,*=debug")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// packagePath takes the package of a PC from function names as the runtime reports them.
+	for _, function := range []string{"gopkg.in/yaml%2ev3.Unmarshal", "gopkg.in/yaml.v3/internal.(*parser).parse"} {
+		if l, ok := ll.Level(functionPackage(function)); !ok || l != slog.LevelWarn {
+			t.Errorf("%s: expected %v, got %v (ok=%v)", function, slog.LevelWarn, l, ok)
+		}
+	}
+}
+
+func TestMiddleware__LoggerLevels(t *testing.T) {
+	buf := new(bytes.Buffer)
+	ll, err := ParseLoggerLevels("db=debug,http.client=error")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	logger := slog.New(NewMiddleware(
+		slog.NewTextHandler,
+		MiddlewareOptions{
+			LoggerLevels: ll,
+			Writer:       buf,
+			HandlerOptions: &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			},
+		},
+	))
+	db := logger.With(LoggerKey, "db.pool")
+	client := logger.With(LoggerKey, "http.client")
+	ctx := context.Background()
+	db.Debug("db debug")
+	client.Warn("client warn")
+	client.Error("client error")
+	logger.Debug("root debug")
+	logger.Info("root info")
+	slog.New(logger.Handler()).DebugContext(With(ctx, LoggerKey, "db"), "ctx debug")
+	logger.WithGroup("req").With(LoggerKey, "db").Debug("group debug")
+	db.WithGroup("req").With(LoggerKey, "http.client").Warn("inner logger warn")
+
+	ll.Set("db=info,github.com/mashiike/slogutils=debug")
+	db.Debug("db debug after set")
+	logger.Debug("package debug after set")
+
+	expected := strings.Join([]string{
+		`level=DEBUG msg="db debug" logger=db.pool`,
+		`level=ERROR msg="client error" logger=http.client`,
+		`level=INFO msg="root info"`,
+		`level=DEBUG msg="ctx debug" logger=db`,
+		`level=DEBUG msg="group debug" req.logger=db`,
+		`level=DEBUG msg="package debug after set"`,
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
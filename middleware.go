@@ -3,9 +3,12 @@ package slogutils
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -14,145 +17,497 @@ import (
 type ModifierFunc func([]byte) []byte
 
 // Color returns a ModifierFunc that colors the log line.
+// Whether the line is colored is decided by the ColorMode of the Middleware, not by color.NoColor.
 func Color(attr ...color.Attribute) ModifierFunc {
 	c := color.New(attr...)
+	c.EnableColor()
 	buf := &bytes.Buffer{}
 	return func(b []byte) []byte {
 		buf.Reset()
-		c.Fprint(buf, string(b))
+		buf.WriteString(c.Sprint(string(b)))
 		return buf.Bytes()
 	}
 }
 
 type modifierWriter struct {
-	f ModifierFunc
-	w io.Writer
+	f        ModifierFunc
+	w        io.Writer
+	level    slog.Level
+	colorize bool
 	sync.Mutex
 }
 
+// levelWriter is implemented by writers that take the level of the line into account, such as AsyncWriter.
+type levelWriter interface {
+	WriteLevel(slog.Level, []byte) (int, error)
+}
+
+func (w *modifierWriter) colorEnabled() bool {
+	return w.colorize
+}
+
 func (w *modifierWriter) Write(b []byte) (int, error) {
-	if w.f == nil {
-		return w.w.Write(b)
+	if w.f != nil {
+		b = w.f(b)
+	}
+	if lw, ok := w.w.(levelWriter); ok {
+		return lw.WriteLevel(w.level, b)
 	}
-	return w.w.Write(w.f(b))
+	return w.w.Write(b)
 }
 
 func (w *modifierWriter) SetModifierFunc(f ModifierFunc) {
 	w.f = f
 }
 
+// ContextExtractorFunc derives attributes from a context, such as the trace ID of the current span.
+type ContextExtractorFunc func(ctx context.Context) []slog.Attr
+
 // MiddlewareOptions are options for creating a Middleware.
 type MiddlewareOptions struct {
 	// ModifierFuncs is a map of log levels to ModifierFunc.
+	// Each level works as a threshold: a record uses the ModifierFunc of the
+	// nearest configured level that is less than or equal to the record's level.
+	// For example, slog.LevelWarn also applies to slog.LevelWarn+2, and a nil
+	// ModifierFunc ends the range of the level below it.
 	ModifierFuncs map[slog.Level]ModifierFunc
 
 	// RecordTransformerFuncs is a list of RecordTransformerFunc.
 	RecordTransformerFuncs []RecordTransformerFunc
 
+	// RecordProcessors is a list of RecordProcessor, applied after RecordTransformerFuncs.
+	// If a RecordProcessor drops a record, the remaining RecordProcessors are not applied.
+	// If a RecordProcessor returns an error, the record is not written and Handle returns the error.
+	RecordProcessors []RecordProcessor
+
+	// Dedup collapses repeated records into one with a repeat count, after all RecordProcessors are applied.
+	// If nil, records are not deduplicated.
+	Dedup *DedupOptions
+
+	// EmitInterval is the interval at which the Middleware asks RecordProcessors implementing RecordEmitter, and Dedup,
+	// for the records that are due, so that summaries are written even when no more records are handled.
+	// Errors of writing them are dropped. If zero, the records are written only with handled records and by Flush.
+	// Call Middleware.Close to stop the timer.
+	EmitInterval time.Duration
+
+	// LoggerLevels overrides the minimum level of HandlerOptions per logger name, such as "db=debug,http.client=warn".
+	// Records whose logger matches no configured name use the level of HandlerOptions.
+	// If nil, all records use the level of HandlerOptions.
+	LoggerLevels *LoggerLevels
+
+	// ContextExtractors derive attributes from the context of each record, in addition to the attributes stored by With.
+	ContextExtractors []ContextExtractorFunc
+
 	// Writer is the writer to write to.
 	Writer io.Writer
 
+	// Async writes to Writer asynchronously through an AsyncWriter with the given options.
+	// If nil, Writer is written synchronously. Call Middleware.Close to write the buffered lines before the program exits.
+	Async *AsyncWriterOptions
+
+	// ColorMode controls whether ModifierFuncs are applied.
+	// By default, they are applied only when Writer is a terminal.
+	ColorMode ColorMode
+
 	// HandlerOptions are options for the handler.
 	HandlerOptions *slog.HandlerOptions
 }
 
 // Middleware is a slog.Handler that modifies log lines.
+//
+// Attributes and groups added by WithAttrs and WithGroup are kept by the Middleware itself
+// instead of being passed to the inner handler, so RecordProcessors see the full attribute set of a record,
+// including the attributes stored in the context by With.
 type Middleware[H slog.Handler] struct {
-	mu                     sync.RWMutex
-	modifierFuncs          map[slog.Level]ModifierFunc
-	recordTransformerFuncs []RecordTransformerFunc
-	opts                   MiddlewareOptions
-	h                      slog.Handler
-	w                      *modifierWriter
-	f                      func(io.Writer, *slog.HandlerOptions) H
+	core *middlewareCore[H]
+	goas []groupOrAttrs
+}
+
+// middlewareCore is the state shared by a Middleware and the handlers derived from it by WithAttrs and WithGroup.
+type middlewareCore[H slog.Handler] struct {
+	mu               sync.RWMutex
+	modifierFuncs    map[slog.Level]ModifierFunc
+	modifierLevels   []slog.Level
+	recordProcessors []RecordProcessor
+	emitters         []recordEmitter
+	dedup            *Deduplicator
+	asyncWriter      *AsyncWriter
+	closer           io.Closer
+	stopEmit         chan struct{}
+	opts             MiddlewareOptions
+	h                slog.Handler
+	w                *modifierWriter
+	f                func(io.Writer, *slog.HandlerOptions) H
+}
+
+// recordEmitter is a RecordEmitter with the index of the RecordProcessors that follow it.
+type recordEmitter struct {
+	RecordEmitter
+	next int
+}
+
+// groupOrAttrs is either a group name added by WithGroup or attributes added by WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
 }
 
 func NewMiddleware[H slog.Handler](f func(io.Writer, *slog.HandlerOptions) H, opts MiddlewareOptions) *Middleware[H] {
+	c := newMiddlewareCore(f, opts)
+	c.startEmitTicker()
+	return &Middleware[H]{core: c}
+}
+
+func newMiddlewareCore[H slog.Handler](f func(io.Writer, *slog.HandlerOptions) H, opts MiddlewareOptions) *middlewareCore[H] {
 	if opts.ModifierFuncs == nil {
 		opts.ModifierFuncs = map[slog.Level]ModifierFunc{}
 	}
-	w := &modifierWriter{w: opts.Writer}
+	handlerOptions := slog.HandlerOptions{}
+	if opts.HandlerOptions != nil {
+		handlerOptions = *opts.HandlerOptions
+	}
+	opts.HandlerOptions = &handlerOptions
+	recordProcessors := make([]RecordProcessor, 0, len(opts.RecordTransformerFuncs)+len(opts.RecordProcessors))
+	for _, f := range opts.RecordTransformerFuncs {
+		recordProcessors = append(recordProcessors, f)
+	}
+	recordProcessors = append(recordProcessors, opts.RecordProcessors...)
+	var emitters []recordEmitter
+	for i, p := range recordProcessors {
+		if e, ok := p.(RecordEmitter); ok {
+			emitters = append(emitters, recordEmitter{RecordEmitter: e, next: i + 1})
+		}
+	}
+	var dedup *Deduplicator
+	if opts.Dedup != nil {
+		dedup = NewDeduplicator(*opts.Dedup)
+		emitters = append(emitters, recordEmitter{RecordEmitter: dedup, next: len(recordProcessors)})
+	}
+	w := &modifierWriter{w: opts.Writer, colorize: opts.ColorMode.Enabled(opts.Writer)}
+	var asyncWriter *AsyncWriter
+	if opts.Async != nil {
+		asyncWriter = NewAsyncWriter(opts.Writer, *opts.Async)
+		w.w = asyncWriter
+	}
 	h := f(w, opts.HandlerOptions)
-	return &Middleware[H]{
-		modifierFuncs:          opts.ModifierFuncs,
-		recordTransformerFuncs: opts.RecordTransformerFuncs,
-		h:                      h,
-		w:                      w,
-		f:                      f,
-		opts:                   opts,
+	return &middlewareCore[H]{
+		modifierFuncs:    opts.ModifierFuncs,
+		modifierLevels:   sortedLevels(opts.ModifierFuncs),
+		recordProcessors: recordProcessors,
+		emitters:         emitters,
+		dedup:            dedup,
+		asyncWriter:      asyncWriter,
+		h:                h,
+		w:                w,
+		f:                f,
+		opts:             opts,
+	}
+}
+
+// reload replaces the state of c, shared by all derived handlers, with the state of nc.
+// The records still held by the old RecordEmitters are written first,
+// and the old AsyncWriter and closer are closed after the replacement.
+func (c *middlewareCore[H]) reload(nc *middlewareCore[H]) error {
+	c.mu.Lock()
+	c.stopEmitTicker()
+	err := c.emit(context.Background(), true)
+	asyncWriter, closer := c.asyncWriter, c.closer
+	c.modifierFuncs = nc.modifierFuncs
+	c.modifierLevels = nc.modifierLevels
+	c.recordProcessors = nc.recordProcessors
+	c.emitters = nc.emitters
+	c.dedup = nc.dedup
+	c.asyncWriter = nc.asyncWriter
+	c.closer = nc.closer
+	c.opts = nc.opts
+	c.h = nc.h
+	c.w = nc.w
+	c.f = nc.f
+	c.startEmitTicker()
+	c.mu.Unlock()
+	if asyncWriter != nil {
+		err = errors.Join(err, asyncWriter.Close())
+	}
+	if closer != nil {
+		err = errors.Join(err, closer.Close())
 	}
+	return err
 }
 
+// SetMinLevel sets the minimum level of the Middleware and of all handlers derived from it.
 func (m *Middleware[H]) SetMinLevel(l slog.Leveler) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.opts.HandlerOptions.Level = l
-	h := m.f(m.w, m.opts.HandlerOptions)
-	m.h = h
+	c := m.core
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts.HandlerOptions.Level = l
+	h := c.f(c.w, c.opts.HandlerOptions)
+	c.h = h
+}
+
+// MinLevel returns the minimum level of the Middleware. If no level is set, it is slog.LevelInfo.
+func (m *Middleware[H]) MinLevel() slog.Level {
+	c := m.core
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.opts.HandlerOptions.Level == nil {
+		return slog.LevelInfo
+	}
+	return c.opts.HandlerOptions.Level.Level()
 }
 
 // Handle implements slog.Handler.
 func (m *Middleware[H]) Handle(ctx context.Context, record slog.Record) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	h := m.h
-	if attrs, ok := attrsFromContext(ctx); ok {
-		h = h.WithAttrs(attrs)
-	}
-	if len(m.recordTransformerFuncs) > 0 {
-		for _, f := range m.recordTransformerFuncs {
-			record = f(record)
+	c := m.core
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	record = m.effectiveRecord(ctx, record)
+	if len(c.recordProcessors) == 0 && c.dedup == nil && c.opts.LoggerLevels == nil {
+		return c.write(ctx, record)
+	}
+	record, keep, err := c.process(ctx, record, c.recordProcessors)
+	if keep && !c.enabled(ctx, record) {
+		keep = false
+	}
+	if keep && c.dedup != nil {
+		_, keep, _ = c.dedup.ProcessRecord(ctx, record)
+	}
+	if emitErr := c.emit(ctx, false); emitErr != nil {
+		return emitErr
+	}
+	if err != nil || !keep {
+		return err
+	}
+	return c.write(ctx, record)
+}
+
+// Flush writes the records that RecordProcessors implementing RecordEmitter still hold, such as pending summaries,
+// and ends the bursts of repeated records held by Dedup.
+// If the writer buffers lines, as AsyncWriter does, Flush also waits until they are written.
+// Call Flush before the program exits.
+func (m *Middleware[H]) Flush(ctx context.Context) error {
+	c := m.core
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	err := c.emit(ctx, true)
+	if f, ok := c.w.w.(interface{ Flush() error }); ok {
+		err = errors.Join(err, f.Flush())
+	}
+	return err
+}
+
+// Close flushes the Middleware, and stops the timer of MiddlewareOptions.EmitInterval
+// and the AsyncWriter created for MiddlewareOptions.Async.
+// It does not close MiddlewareOptions.Writer, except for the file opened by NewMiddlewareFromEnv.
+func (m *Middleware[H]) Close() error {
+	c := m.core
+	c.mu.Lock()
+	c.stopEmitTicker()
+	c.mu.Unlock()
+	err := m.Flush(context.Background())
+	c.mu.Lock()
+	asyncWriter, closer := c.asyncWriter, c.closer
+	c.closer = nil
+	c.mu.Unlock()
+	if asyncWriter != nil {
+		err = errors.Join(err, asyncWriter.Close())
+	}
+	if closer != nil {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+// Dropped returns the number of lines dropped by the writer, such as by the OverflowPolicy of an AsyncWriter.
+func (m *Middleware[H]) Dropped() uint64 {
+	c := m.core
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if d, ok := c.w.w.(interface{ Dropped() uint64 }); ok {
+		return d.Dropped()
+	}
+	return 0
+}
+
+func (c *middlewareCore[H]) process(ctx context.Context, record slog.Record, processors []RecordProcessor) (slog.Record, bool, error) {
+	for _, p := range processors {
+		var keep bool
+		var err error
+		record, keep, err = p.ProcessRecord(ctx, record)
+		if err != nil || !keep {
+			return record, false, err
 		}
-		if !m.h.Enabled(ctx, record.Level) {
-			return nil
+	}
+	return record, true, nil
+}
+
+// emit writes the records emitted by RecordEmitters, after the RecordProcessors that follow each RecordEmitter.
+func (c *middlewareCore[H]) emit(ctx context.Context, flush bool) error {
+	var errs []error
+	for _, e := range c.emitters {
+		for _, r := range e.EmitRecords(flush) {
+			r, keep, err := c.process(ctx, r, c.recordProcessors[e.next:])
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if !keep || !c.enabled(ctx, r) {
+				continue
+			}
+			if err := c.write(ctx, r); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
-	m.w.Lock()
-	defer m.w.Unlock()
-	m.w.SetModifierFunc(m.modifierFuncs[record.Level])
-	return h.Handle(ctx, record)
+	return errors.Join(errs...)
 }
 
-// Clone returns a new Middleware with the same Handler and modifierFuncs.
+// startEmitTicker starts writing the records that are due every opts.EmitInterval, until stopEmitTicker is called.
+// The caller must hold c.mu for writing.
+func (c *middlewareCore[H]) startEmitTicker() {
+	if c.opts.EmitInterval <= 0 || len(c.emitters) == 0 {
+		return
+	}
+	stop := make(chan struct{})
+	c.stopEmit = stop
+	ticker := time.NewTicker(c.opts.EmitInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.mu.RLock()
+				select {
+				case <-stop:
+				default:
+					_ = c.emit(context.Background(), false)
+				}
+				c.mu.RUnlock()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopEmitTicker stops the timer started by startEmitTicker. The caller must hold c.mu for writing.
+func (c *middlewareCore[H]) stopEmitTicker() {
+	if c.stopEmit != nil {
+		close(c.stopEmit)
+		c.stopEmit = nil
+	}
+}
+
+// enabled reports whether record is at or above the level of its logger in LoggerLevels, or of the handler.
+func (c *middlewareCore[H]) enabled(ctx context.Context, record slog.Record) bool {
+	if c.opts.LoggerLevels != nil {
+		if l, ok := c.opts.LoggerLevels.recordLevel(record); ok {
+			return record.Level >= l
+		}
+	}
+	return c.h.Enabled(ctx, record.Level)
+}
+
+func (c *middlewareCore[H]) write(ctx context.Context, record slog.Record) error {
+	c.w.Lock()
+	defer c.w.Unlock()
+	c.w.SetModifierFunc(c.modifierFunc(record.Level))
+	c.w.level = record.Level
+	return c.h.Handle(ctx, record)
+}
+
+// effectiveRecord returns a copy of record that also holds the attributes and groups added by WithAttrs and WithGroup,
+// the attributes stored in ctx by With, and the attributes derived by ContextExtractors.
+// The context attributes belong to the innermost group, as if they were added just before the record was logged.
+func (m *Middleware[H]) effectiveRecord(ctx context.Context, record slog.Record) slog.Record {
+	ctxAttrs, _ := attrsFromContext(ctx)
+	for _, extract := range m.core.opts.ContextExtractors {
+		ctxAttrs = append(ctxAttrs[:len(ctxAttrs):len(ctxAttrs)], extract(ctx)...)
+	}
+	if len(m.goas) == 0 && len(ctxAttrs) == 0 {
+		return record
+	}
+	attrs := make([]slog.Attr, 0, len(ctxAttrs)+record.NumAttrs())
+	attrs = append(attrs, ctxAttrs...)
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	for i := len(m.goas) - 1; i >= 0; i-- {
+		goa := m.goas[i]
+		if goa.group != "" {
+			if len(attrs) > 0 {
+				attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+			}
+			continue
+		}
+		attrs = append(append(make([]slog.Attr, 0, len(goa.attrs)+len(attrs)), goa.attrs...), attrs...)
+	}
+	return withAttrs(record, attrs)
+}
+
+// modifierFunc returns the ModifierFunc of the nearest configured level at or below l.
+// It returns nil if colors are disabled for the writer.
+func (c *middlewareCore[H]) modifierFunc(l slog.Level) ModifierFunc {
+	if !c.w.colorize {
+		return nil
+	}
+	i := sort.Search(len(c.modifierLevels), func(i int) bool {
+		return c.modifierLevels[i] > l
+	})
+	if i == 0 {
+		return nil
+	}
+	return c.modifierFuncs[c.modifierLevels[i-1]]
+}
+
+func sortedLevels(m map[slog.Level]ModifierFunc) []slog.Level {
+	levels := make([]slog.Level, 0, len(m))
+	for l := range m {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	return levels
+}
+
+// Clone returns a new Middleware with the same Handler, modifierFuncs, attributes and groups.
 func (m *Middleware[H]) Clone() *Middleware[H] {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	modifierFuncs := make(map[slog.Level]ModifierFunc, len(m.modifierFuncs))
-	for k, v := range m.modifierFuncs {
-		modifierFuncs[k] = v
-	}
-	recordTransformerFuncs := make([]RecordTransformerFunc, len(m.recordTransformerFuncs))
-	copy(recordTransformerFuncs, m.recordTransformerFuncs)
+	goas := make([]groupOrAttrs, len(m.goas))
+	copy(goas, m.goas)
 	return &Middleware[H]{
-		modifierFuncs:          modifierFuncs,
-		recordTransformerFuncs: recordTransformerFuncs,
-		h:                      m.h,
-		w:                      m.w,
+		core: m.core,
+		goas: goas,
 	}
 }
 
 func (m *Middleware[H]) Enabled(ctx context.Context, l slog.Level) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	if len(m.recordTransformerFuncs) > 0 {
+	c := m.core
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.recordProcessors) > 0 {
 		return true
 	}
-	return m.h.Enabled(ctx, l)
+	if c.opts.LoggerLevels != nil {
+		if lo, ok := c.opts.LoggerLevels.minLevel(); ok && l >= lo {
+			return true
+		}
+	}
+	return c.h.Enabled(ctx, l)
 }
 
 func (m *Middleware[H]) WithAttrs(as []slog.Attr) slog.Handler {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	if len(as) == 0 {
+		return m
+	}
 	c := m.Clone()
-	c.h = c.h.WithAttrs(as)
+	c.goas = append(c.goas, groupOrAttrs{attrs: as})
 	return c
 }
 
 func (m *Middleware[H]) WithGroup(name string) slog.Handler {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	if name == "" {
+		return m
+	}
 	c := m.Clone()
-	c.h = c.h.WithGroup(name)
+	c.goas = append(c.goas, groupOrAttrs{group: name})
 	return c
 }
 
@@ -13,8 +13,6 @@ import (
 )
 
 func TestMiddleware__WithColor(t *testing.T) {
-	color.NoColor = false
-
 	buf := new(bytes.Buffer)
 	middleware := NewMiddleware(
 		slog.NewJSONHandler,
@@ -25,7 +23,8 @@ func TestMiddleware__WithColor(t *testing.T) {
 				slog.LevelWarn:  Color(color.FgYellow),
 				slog.LevelError: Color(color.FgRed, color.Bold),
 			},
-			Writer: buf,
+			Writer:    buf,
+			ColorMode: ColorModeAlways,
 			HandlerOptions: &slog.HandlerOptions{
 				Level: slog.LevelWarn,
 			},
@@ -73,7 +72,6 @@ func TestMiddleware__WithColor(t *testing.T) {
 }
 
 func TestMiddleware__SetMinLevel(t *testing.T) {
-	color.NoColor = true
 	buf := new(bytes.Buffer)
 	middleware := NewMiddleware(
 		slog.NewJSONHandler,
@@ -135,8 +133,6 @@ func TestMiddleware__SetMinLevel(t *testing.T) {
 }
 
 func TestMiddleware__WithRecordTransformer(t *testing.T) {
-	color.NoColor = true
-
 	buf := new(bytes.Buffer)
 	middleware := NewMiddleware(
 		slog.NewJSONHandler,
@@ -187,8 +183,6 @@ func TestMiddleware__WithRecordTransformer(t *testing.T) {
 }
 
 func TestMiddleware__LoggerWith(t *testing.T) {
-	color.NoColor = false
-
 	buf := new(bytes.Buffer)
 	middleware := NewMiddleware(
 		slog.NewJSONHandler,
@@ -199,7 +193,8 @@ func TestMiddleware__LoggerWith(t *testing.T) {
 				slog.LevelWarn:  Color(color.FgYellow),
 				slog.LevelError: Color(color.FgRed, color.Bold),
 			},
-			Writer: buf,
+			Writer:    buf,
+			ColorMode: ColorModeAlways,
 			HandlerOptions: &slog.HandlerOptions{
 				Level: slog.LevelWarn,
 			},
@@ -290,8 +285,6 @@ func jsonEqualSlice(a, b []interface{}) bool {
 }
 
 func TestMiddleware__WithConvertLegacyLevel(t *testing.T) {
-	color.NoColor = true
-
 	buf := new(bytes.Buffer)
 	middleware := NewMiddleware(
 		slog.NewJSONHandler,
@@ -350,3 +343,97 @@ func TestMiddleware__WithConvertLegacyLevel(t *testing.T) {
 	}
 	t.Log(result)
 }
+
+func TestMiddleware__ModifierFuncsLevelThreshold(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			ModifierFuncs: map[slog.Level]ModifierFunc{
+				slog.LevelInfo:      nil,
+				slog.LevelWarn:      Color(color.FgYellow),
+				slog.LevelError:     Color(color.FgRed),
+				slog.LevelError + 4: Color(color.FgMagenta),
+			},
+			Writer:    buf,
+			ColorMode: ColorModeAlways,
+			HandlerOptions: &slog.HandlerOptions{
+				Level: slog.LevelDebug,
+			},
+		},
+	)
+	logger := slog.New(middleware)
+	ctx := context.Background()
+	cases := []struct {
+		level  slog.Level
+		prefix string
+	}{
+		{slog.LevelDebug, "{"},
+		{slog.LevelInfo + 2, "{"},
+		{slog.LevelWarn, "\x1b[33m"},
+		{slog.LevelWarn + 2, "\x1b[33m"},
+		{slog.LevelError, "\x1b[31m"},
+		{slog.LevelError + 4, "\x1b[35m"},
+		{slog.LevelError + 8, "\x1b[35m"},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		logger.Log(ctx, c.level, "foo")
+		if !strings.HasPrefix(buf.String(), c.prefix) {
+			t.Errorf("level %v: expected prefix %q, got %q", c.level, c.prefix, buf.String())
+		}
+	}
+}
+
+func TestMiddleware__TransformerSeesWithAttrs(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			RecordTransformerFuncs: []RecordTransformerFunc{
+				DropAttrs("password", "user.password"),
+			},
+			Writer: buf,
+			HandlerOptions: &slog.HandlerOptions{
+				Level: slog.LevelInfo,
+			},
+		},
+	)
+	logger := slog.New(middleware)
+	ctx := With(context.Background(), slog.Int64("request_id", 12))
+	logger.With("password", "HIDDEN_VALUE", "app", "foo").
+		WithGroup("user").With("password", "HIDDEN_VALUE", "name", "bar").
+		InfoContext(ctx, "buzz", "id", 3)
+	var actualObj, expectedObj map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &actualObj); err != nil {
+		t.Fatalf("failed to unmarshal actual %q: %s", buf.String(), err)
+	}
+	expected := `{"level":"INFO","msg":"buzz","app":"foo","user":{"name":"bar","request_id":12,"id":3}}`
+	if err := json.Unmarshal([]byte(expected), &expectedObj); err != nil {
+		t.Fatalf("failed to unmarshal expected %q: %s", expected, err)
+	}
+	delete(actualObj, "time")
+	if !jsonEqual(actualObj, expectedObj) {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestMiddleware__SetMinLevelAffectsDerivedHandlers(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			Writer: buf,
+		},
+	)
+	logger := slog.New(middleware).With("app", "foo")
+	logger.Debug("baz")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug record to be dropped, got %q", buf.String())
+	}
+	middleware.SetMinLevel(slog.LevelDebug)
+	logger.Debug("baz")
+	if !strings.Contains(buf.String(), `"msg":"baz"`) {
+		t.Errorf("expected debug record to be written, got %q", buf.String())
+	}
+}
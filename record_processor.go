@@ -0,0 +1,52 @@
+package slogutils
+
+import (
+	"context"
+	"log/slog"
+)
+
+// RecordProcessor processes a slog.Record in the Middleware pipeline.
+// Unlike RecordTransformerFunc, it can drop the record by returning false, or report a failure by returning an error.
+type RecordProcessor interface {
+	ProcessRecord(ctx context.Context, r slog.Record) (slog.Record, bool, error)
+}
+
+// RecordEmitter is implemented by RecordProcessors that produce records of their own, such as summaries.
+// The Middleware asks for the emitted records after each processed record and writes them before that record,
+// after passing them through the RecordProcessors that follow the RecordEmitter.
+type RecordEmitter interface {
+	// EmitRecords returns the records that are due.
+	// If flush is true, all pending records are returned, because no more records may follow.
+	EmitRecords(flush bool) []slog.Record
+}
+
+// RecordProcessorFunc is a function that implements RecordProcessor.
+type RecordProcessorFunc func(ctx context.Context, r slog.Record) (slog.Record, bool, error)
+
+// ProcessRecord implements RecordProcessor.
+func (f RecordProcessorFunc) ProcessRecord(ctx context.Context, r slog.Record) (slog.Record, bool, error) {
+	return f(ctx, r)
+}
+
+// ProcessRecord implements RecordProcessor. A RecordTransformerFunc always keeps the record.
+func (f RecordTransformerFunc) ProcessRecord(_ context.Context, r slog.Record) (slog.Record, bool, error) {
+	return f(r), true, nil
+}
+
+// FilterRecords returns a RecordProcessorFunc that keeps only the records for which keep returns true.
+func FilterRecords(keep func(context.Context, slog.Record) bool) RecordProcessorFunc {
+	return func(ctx context.Context, r slog.Record) (slog.Record, bool, error) {
+		return r, keep(ctx, r), nil
+	}
+}
+
+// ValidateRecords returns a RecordProcessorFunc that reports the error returned by validate.
+// A record that fails validation is not written.
+func ValidateRecords(validate func(context.Context, slog.Record) error) RecordProcessorFunc {
+	return func(ctx context.Context, r slog.Record) (slog.Record, bool, error) {
+		if err := validate(ctx, r); err != nil {
+			return r, false, err
+		}
+		return r, true, nil
+	}
+}
@@ -0,0 +1,93 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterRecords(t *testing.T) {
+	p := FilterRecords(func(_ context.Context, r slog.Record) bool {
+		return !strings.HasPrefix(r.Message, "health")
+	})
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "healthcheck", 0)
+	if _, keep, err := p.ProcessRecord(context.Background(), r); keep || err != nil {
+		t.Errorf("expected record to be dropped, got keep=%v err=%v", keep, err)
+	}
+	r.Message = "request"
+	if _, keep, err := p.ProcessRecord(context.Background(), r); !keep || err != nil {
+		t.Errorf("expected record to be kept, got keep=%v err=%v", keep, err)
+	}
+}
+
+func TestRecordTransformerFunc__ProcessRecord(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "TestRecordTransformerFunc", 0)
+	r.AddAttrs(slog.String("foo", "foo"))
+	actual, keep, err := DropAttrs("foo").ProcessRecord(context.Background(), r)
+	if !keep || err != nil {
+		t.Fatalf("expected record to be kept, got keep=%v err=%v", keep, err)
+	}
+	if actual.NumAttrs() != 0 {
+		t.Errorf("expected attrs to be dropped, got %d attrs", actual.NumAttrs())
+	}
+}
+
+func TestMiddleware__WithRecordProcessors(t *testing.T) {
+	errInvalid := errors.New("user_id is required")
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			RecordTransformerFuncs: []RecordTransformerFunc{
+				DropAttrs("secrets"),
+			},
+			RecordProcessors: []RecordProcessor{
+				FilterRecords(func(_ context.Context, r slog.Record) bool {
+					return r.Message != "noisy"
+				}),
+				ValidateRecords(func(_ context.Context, r slog.Record) error {
+					if r.Level < slog.LevelError {
+						return nil
+					}
+					var found bool
+					r.Attrs(func(a slog.Attr) bool {
+						found = a.Key == "user_id"
+						return !found
+					})
+					if !found {
+						return errInvalid
+					}
+					return nil
+				}),
+			},
+			Writer: buf,
+			HandlerOptions: &slog.HandlerOptions{
+				Level: slog.LevelInfo,
+			},
+		},
+	)
+	ctx := context.Background()
+	logger := slog.New(middleware)
+	logger.InfoContext(ctx, "noisy")
+	logger.InfoContext(ctx, "foo", "secrets", "HIDDEN_VALUE")
+	if err := middleware.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelError, "bar", 0)); !errors.Is(err, errInvalid) {
+		t.Errorf("expected error %v, got %v", errInvalid, err)
+	}
+	result := buf.String()
+	if strings.Contains(result, "noisy") {
+		t.Errorf("expected noisy record to be dropped, got %q", result)
+	}
+	if strings.Contains(result, "HIDDEN_VALUE") {
+		t.Errorf("expected secrets to be dropped, got %q", result)
+	}
+	if strings.Contains(result, "bar") {
+		t.Errorf("expected invalid record not to be written, got %q", result)
+	}
+	if !strings.Contains(result, `"msg":"foo"`) {
+		t.Errorf("expected foo record to be written, got %q", result)
+	}
+}
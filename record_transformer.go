@@ -1,6 +1,7 @@
 package slogutils
 
 import (
+	"fmt"
 	"log/slog"
 	"strings"
 )
@@ -9,78 +10,169 @@ import (
 type RecordTransformerFunc func(slog.Record) slog.Record
 
 // DefaultAttrs returns a RecordTransformerFunc that adds the given attributes to a slog.Record if they don't already exist.
-func DefaultAttrs(args ...any) func(slog.Record) slog.Record {
+// A key may be a dotted path such as "http.request.method" that addresses an attribute inside groups.
+// If the attribute does not exist, it is added into the deepest existing group on the path,
+// or added with the key as is when no group on the path exists.
+func DefaultAttrs(args ...any) RecordTransformerFunc {
 	attrs := argsToAttrs(args)
 	return func(r slog.Record) slog.Record {
-		exits := make(map[string]bool, len(attrs))
-		r.Attrs(func(a slog.Attr) bool {
-			exits[a.Key] = true
-			return true
-		})
-		notExits := make([]slog.Attr, 0, len(attrs))
+		current := recordAttrs(r)
+		changed := false
 		for _, a := range attrs {
-			if !exits[a.Key] {
-				notExits = append(notExits, a)
+			if !hasAttrPath(current, a.Key) {
+				current = insertAttr(current, a.Key, a)
+				changed = true
 			}
 		}
-		r.AddAttrs(notExits...)
-		return r
+		if !changed {
+			return r
+		}
+		return withAttrs(r, current)
 	}
 }
 
 // DropAttrs returns a RecordTransformerFunc that drops the given attributes from a slog.Record.
-func DropAttrs(keys ...string) func(slog.Record) slog.Record {
+// A key may be a dotted path such as "http.request.headers.authorization" that addresses an attribute inside groups.
+func DropAttrs(keys ...string) RecordTransformerFunc {
+	paths := newAttrPaths(keys)
 	return func(r slog.Record) slog.Record {
-		attrs := make([]slog.Attr, 0, len(keys))
-		r.Attrs(func(a slog.Attr) bool {
-			for _, key := range keys {
-				if a.Key == key {
-					return true
-				}
-			}
-			attrs = append(attrs, a)
-			return true
+		attrs := editAttrs(recordAttrs(r), paths, func(a slog.Attr, _ attrPath) (slog.Attr, bool) {
+			return a, false
 		})
-		c := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
-		c.AddAttrs(attrs...)
-		return c
+		return withAttrs(r, attrs)
 	}
 }
 
 // RenameAttrs returns a RecordTransformerFunc that renames the given attributes from a slog.Record.
-func RenameAttrs(m map[string]string) func(slog.Record) slog.Record {
+// A key of m may be a dotted path such as "http.request.headers.authorization" that addresses an attribute inside groups.
+// The renamed attribute stays in the same group, so the value of m is the new key, not a path.
+func RenameAttrs(m map[string]string) RecordTransformerFunc {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	paths := newAttrPaths(keys)
 	return func(r slog.Record) slog.Record {
-		attrs := make([]slog.Attr, 0, len(m))
-		r.Attrs(func(a slog.Attr) bool {
-			if key, ok := m[a.Key]; ok {
-				attrs = append(attrs, slog.Attr{Key: key, Value: a.Value})
-				return true
-			}
-			attrs = append(attrs, a)
-			return true
+		attrs := editAttrs(recordAttrs(r), paths, func(a slog.Attr, p attrPath) (slog.Attr, bool) {
+			return slog.Attr{Key: m[p.full], Value: a.Value}, true
 		})
-		c := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
-		c.AddAttrs(attrs...)
-		return c
+		return withAttrs(r, attrs)
 	}
 }
 
+// DuplicatePolicy decides how UniqueAttrsWithPolicy resolves attributes with the same key.
+type DuplicatePolicy int
+
+const (
+	// DuplicateLastWins keeps the value of the last attribute.
+	DuplicateLastWins DuplicatePolicy = iota
+	// DuplicateFirstWins keeps the value of the first attribute.
+	DuplicateFirstWins
+	// DuplicateMerge merges the values of all attributes into a slice.
+	DuplicateMerge
+	// DuplicateSuffix keeps all attributes, renaming the later ones to key#2, key#3 and so on,
+	// skipping the keys that the record already has.
+	DuplicateSuffix
+)
+
 // UniqueAttrs returns a RecordTransformerFunc that removes duplicate attributes from a slog.Record.
-func UniqueAttrs() func(slog.Record) slog.Record {
+// The value of the last attribute wins. It is the same as UniqueAttrsWithPolicy(DuplicateLastWins).
+func UniqueAttrs() RecordTransformerFunc {
+	return UniqueAttrsWithPolicy(DuplicateLastWins)
+}
+
+// UniqueAttrsWithPolicy returns a RecordTransformerFunc that resolves duplicate attributes of a slog.Record by the policy.
+// Attributes keep the order in which their keys first appear.
+// Groups with the same key are merged into one group, and attributes inside groups are deduplicated within their group.
+func UniqueAttrsWithPolicy(policy DuplicatePolicy) RecordTransformerFunc {
 	return func(r slog.Record) slog.Record {
-		attrMap := make(map[string]slog.Value)
-		r.Attrs(func(a slog.Attr) bool {
-			attrMap[a.Key] = a.Value
-			return true
-		})
-		attrs := make([]slog.Attr, 0, len(attrMap))
-		for k, v := range attrMap {
-			attrs = append(attrs, slog.Attr{Key: k, Value: v})
+		return withAttrs(r, uniqueAttrs(recordAttrs(r), policy))
+	}
+}
+
+func uniqueAttrs(as []slog.Attr, policy DuplicatePolicy) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(as))
+	index := make(map[string]int, len(as))
+	suffixes := make(map[string]int, len(as))
+	merged := make(map[string][]any)
+	as = inlineGroups(as)
+	var taken map[string]bool
+	if policy == DuplicateSuffix {
+		taken = make(map[string]bool, len(as))
+		for _, a := range as {
+			taken[a.Key] = true
+		}
+	}
+	for _, a := range as {
+		i, ok := index[a.Key]
+		if !ok {
+			index[a.Key] = len(attrs)
+			suffixes[a.Key] = 1
+			attrs = append(attrs, a)
+			continue
 		}
-		c := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
-		c.AddAttrs(attrs...)
-		return c
+		prev, v := attrs[i].Value.Resolve(), a.Value.Resolve()
+		if prev.Kind() == slog.KindGroup && v.Kind() == slog.KindGroup {
+			grouped := append(append(make([]slog.Attr, 0, len(prev.Group())+len(v.Group())), prev.Group()...), v.Group()...)
+			attrs[i].Value = slog.GroupValue(grouped...)
+			continue
+		}
+		switch policy {
+		case DuplicateFirstWins:
+		case DuplicateMerge:
+			if _, ok := merged[a.Key]; !ok {
+				merged[a.Key] = []any{prev.Any()}
+			}
+			merged[a.Key] = append(merged[a.Key], v.Any())
+		case DuplicateSuffix:
+			key := a.Key
+			for n := suffixes[a.Key] + 1; taken[key]; n++ {
+				key = fmt.Sprintf("%s#%d", a.Key, n)
+				suffixes[a.Key] = n
+			}
+			taken[key] = true
+			attrs = append(attrs, slog.Attr{Key: key, Value: a.Value})
+		default:
+			attrs[i].Value = a.Value
+		}
+	}
+	for key, values := range merged {
+		attrs[index[key]].Value = slog.AnyValue(values)
 	}
+	for i, a := range attrs {
+		if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+			attrs[i].Value = slog.GroupValue(uniqueAttrs(v.Group(), policy)...)
+		}
+	}
+	return attrs
+}
+
+// inlineGroups replaces groups with an empty key by their attributes, as handlers do.
+func inlineGroups(as []slog.Attr) []slog.Attr {
+	var inlined []slog.Attr
+	for i, a := range as {
+		if a.Key != "" {
+			if inlined != nil {
+				inlined = append(inlined, a)
+			}
+			continue
+		}
+		v := a.Value.Resolve()
+		if v.Kind() != slog.KindGroup {
+			if inlined != nil {
+				inlined = append(inlined, a)
+			}
+			continue
+		}
+		if inlined == nil {
+			inlined = append(make([]slog.Attr, 0, len(as)), as[:i]...)
+		}
+		inlined = append(inlined, inlineGroups(v.Group())...)
+	}
+	if inlined == nil {
+		return as
+	}
+	return inlined
 }
 
 // ConvertLegacyLevel returns a RecordTransformerFunc that converts legacy level to slog.Level.
@@ -94,7 +186,7 @@ func UniqueAttrs() func(slog.Record) slog.Record {
 //
 //	ConvertLegacyLevel(map[string]slog.Level{"debug": slog.LevelDebug}, true)
 //	If the message is "[DEBUG] hello world", the slog.Record.Level is converted to slog.LevelDebug.
-func ConvertLegacyLevel(levelMap map[string]slog.Level, caseInsensitive bool) func(slog.Record) slog.Record {
+func ConvertLegacyLevel(levelMap map[string]slog.Level, caseInsensitive bool) RecordTransformerFunc {
 	if levelMap == nil {
 		return func(r slog.Record) slog.Record { return r }
 	}
@@ -129,3 +221,12 @@ func ConvertLegacyLevel(levelMap map[string]slog.Level, caseInsensitive bool) fu
 		return r
 	}
 }
+
+// ZeroPC returns a RecordTransformerFunc that clears the PC of a slog.Record,
+// so that handlers omit the source even with HandlerOptions.AddSource, for deterministic output in examples and tests.
+func ZeroPC() RecordTransformerFunc {
+	return func(r slog.Record) slog.Record {
+		r.PC = 0
+		return r
+	}
+}
@@ -2,6 +2,7 @@ package slogutils
 
 import (
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 )
@@ -111,3 +112,162 @@ func TestConvertLegacyLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestDropAttrs__Nested(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "TestDropAttrs__Nested", 0)
+	r.AddAttrs(
+		slog.Group("http",
+			slog.Group("request",
+				slog.String("method", "GET"),
+				slog.Group("headers", slog.String("authorization", "Bearer xxx"), slog.String("accept", "*/*")),
+			),
+		),
+		slog.String("http.request.headers.authorization", "literal"),
+	)
+	r = DropAttrs("http.request.headers.authorization")(r)
+	actual := flattenRecordAttrs(r)
+	if _, ok := actual["http.request.headers.authorization"]; ok {
+		t.Errorf("expected http.request.headers.authorization to be dropped, got %v", actual)
+	}
+	if actual["http.request.headers.accept"] != "*/*" || actual["http.request.method"] != "GET" {
+		t.Errorf("expected other attrs to be kept, got %v", actual)
+	}
+}
+
+func TestRenameAttrs__Nested(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "TestRenameAttrs__Nested", 0)
+	r.AddAttrs(slog.Group("http", slog.Group("request", slog.String("method", "GET"))))
+	r = RenameAttrs(map[string]string{"http.request.method": "verb"})(r)
+	actual := flattenRecordAttrs(r)
+	if _, ok := actual["http.request.method"]; ok || actual["http.request.verb"] != "GET" {
+		t.Errorf("expected http.request.method to be renamed to verb, got %v", actual)
+	}
+}
+
+func TestDefaultAttrs__Nested(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "TestDefaultAttrs__Nested", 0)
+	r.AddAttrs(slog.Group("http", slog.Group("request", slog.String("method", "GET"))))
+	r = DefaultAttrs(
+		"http.request.method", "POST",
+		"http.request.proto", "HTTP/1.1",
+		"service.name", "app",
+	)(r)
+	actual := flattenRecordAttrs(r)
+	expected := map[string]string{
+		"http.request.method": "GET",
+		"http.request.proto":  "HTTP/1.1",
+		"service.name":        "app",
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("expected %s=%s, got %v", k, v, actual)
+		}
+	}
+}
+
+func TestUniqueAttrs__Nested(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "TestUniqueAttrs__Nested", 0)
+	r.AddAttrs(slog.Group("http", slog.String("method", "GET"), slog.String("method", "POST")))
+	r = UniqueAttrs()(r)
+	var count int
+	r.Attrs(func(a slog.Attr) bool {
+		for _, ga := range a.Value.Group() {
+			if ga.Key == "method" {
+				count++
+			}
+		}
+		return true
+	})
+	if count != 1 {
+		t.Errorf("expected one http.method, got %d", count)
+	}
+}
+
+func flattenRecordAttrs(r slog.Record) map[string]string {
+	flatten := make(map[string]string)
+	var walk func(prefix string, as []slog.Attr)
+	walk = func(prefix string, as []slog.Attr) {
+		for _, a := range as {
+			key := prefix + a.Key
+			if a.Value.Kind() == slog.KindGroup {
+				walk(key+".", a.Value.Group())
+				continue
+			}
+			flatten[key] = a.Value.Resolve().String()
+		}
+	}
+	var as []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		as = append(as, a)
+		return true
+	})
+	walk("", as)
+	return flatten
+}
+
+func TestUniqueAttrsWithPolicy(t *testing.T) {
+	cases := []struct {
+		policy   DuplicatePolicy
+		expected []string
+	}{
+		{DuplicateLastWins, []string{"foo=3", "http=[method=POST path=/]", "bar=2"}},
+		{DuplicateFirstWins, []string{"foo=1", "http=[method=GET path=/]", "bar=2"}},
+		{DuplicateMerge, []string{"foo=[1 3]", "http=[method=[GET POST] path=/]", "bar=2"}},
+		{DuplicateSuffix, []string{"foo=1", "http=[method=GET method#2=POST path=/]", "bar=2", "foo#2=3"}},
+	}
+	for _, c := range cases {
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "TestUniqueAttrsWithPolicy", 0)
+		r.AddAttrs(
+			slog.Int("foo", 1),
+			slog.Group("http", slog.String("method", "GET")),
+			slog.Int("bar", 2),
+			slog.Group("", slog.Int("foo", 3)),
+			slog.Group("http", slog.String("method", "POST"), slog.String("path", "/")),
+		)
+		r = UniqueAttrsWithPolicy(c.policy)(r)
+		var actual []string
+		r.Attrs(func(a slog.Attr) bool {
+			actual = append(actual, a.String())
+			return true
+		})
+		if strings.Join(actual, " ") != strings.Join(c.expected, " ") {
+			t.Errorf("policy %d: expected %v, got %v", c.policy, c.expected, actual)
+		}
+	}
+}
+
+func TestUniqueAttrsWithPolicy__SuffixTaken(t *testing.T) {
+	cases := []struct {
+		attrs    []slog.Attr
+		expected string
+	}{
+		{
+			[]slog.Attr{slog.Int("a", 1), slog.String("a#2", "x"), slog.Int("a", 2)},
+			"a=1 a#2=x a#3=2",
+		},
+		{
+			[]slog.Attr{slog.Int("a", 1), slog.Int("a", 2), slog.String("a#2", "x"), slog.Int("a", 3)},
+			"a=1 a#3=2 a#2=x a#4=3",
+		},
+		{
+			[]slog.Attr{slog.Group("a", slog.Int("b", 1)), slog.Group("a", slog.Int("c", 2)), slog.Int("a", 3)},
+			"a=[b=1 c=2] a#2=3",
+		},
+	}
+	for _, c := range cases {
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "TestUniqueAttrsWithPolicy__SuffixTaken", 0)
+		r.AddAttrs(c.attrs...)
+		r = UniqueAttrsWithPolicy(DuplicateSuffix)(r)
+		var actual []string
+		r.Attrs(func(a slog.Attr) bool {
+			actual = append(actual, a.String())
+			return true
+		})
+		if strings.Join(actual, " ") != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, strings.Join(actual, " "))
+		}
+	}
+}
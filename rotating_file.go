@@ -0,0 +1,324 @@
+package slogutils
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RotatingFileOptions are options for NewRotatingFile.
+type RotatingFileOptions struct {
+	// MaxSize is the size in bytes at which the file is rotated. If zero, the file is not rotated by size.
+	MaxSize int64
+
+	// Interval rotates the file when the time crosses a multiple of Interval, such as every hour or every 24 hours (at midnight UTC).
+	// If zero, the file is not rotated by time.
+	Interval time.Duration
+
+	// MaxBackups is the number of rotated files to keep, named path.1, path.2 and so on, newest first.
+	// If zero, all rotated files are kept.
+	MaxBackups int
+
+	// Compress gzips rotated files, named path.1.gz and so on.
+	// Files are compressed in the background, and a failure is reported by the next Rotate or by Close.
+	Compress bool
+
+	// ReopenOnSIGHUP reopens the file when the process receives SIGHUP, so that external tools can move it away.
+	ReopenOnSIGHUP bool
+
+	// Perm is the permission of created files. If zero, 0644 is used.
+	Perm os.FileMode
+
+	// Now returns the current time. If nil, time.Now is used, and an existing file belongs to the interval in which it was last modified.
+	// Otherwise, an existing file belongs to the interval in which it is opened, because its modification time is not on the clock of Now.
+	Now func() time.Time
+}
+
+// RotatingFile is an io.Writer that writes to a file and rotates it by size and time.
+// It can be used as MiddlewareOptions.Writer.
+type RotatingFile struct {
+	mu          sync.Mutex
+	path        string
+	opts        RotatingFileOptions
+	useModTime  bool
+	f           *os.File
+	closed      bool
+	size        int64
+	openedAt    time.Time
+	compression sync.WaitGroup
+	compressErr error
+	rotateErr   error
+	sig         chan os.Signal
+	done        chan struct{}
+}
+
+// NewRotatingFile opens the file at path for appending, creating it if needed, and returns a RotatingFile that writes to it.
+func NewRotatingFile(path string, opts RotatingFileOptions) (*RotatingFile, error) {
+	if opts.Perm == 0 {
+		opts.Perm = 0644
+	}
+	useModTime := opts.Now == nil
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	rf := &RotatingFile{
+		path:       path,
+		opts:       opts,
+		useModTime: useModTime,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	if opts.ReopenOnSIGHUP {
+		rf.sig = make(chan os.Signal, 1)
+		rf.done = make(chan struct{})
+		signal.Notify(rf.sig, syscall.SIGHUP)
+		go rf.watchSignal()
+	}
+	return rf, nil
+}
+
+// Write implements io.Writer. It rotates the file before writing if needed.
+// If the rotation fails but a file is open, b is still written, and the error of the last failed rotation is reported by Close.
+// If the file could not be opened again by the last rotation or Reopen, Write tries to open it first.
+func (rf *RotatingFile) Write(b []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.shouldRotate(int64(len(b))) {
+		if err := rf.rotate(); err != nil {
+			if rf.f == nil {
+				return 0, err
+			}
+			rf.rotateErr = err
+		}
+	}
+	n, err := rf.f.Write(b)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now.
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	return rf.rotate()
+}
+
+// Reopen closes the file and opens the file at the same path again.
+// The file is opened even if it cannot be closed.
+func (rf *RotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	return errors.Join(rf.closeFile(), rf.open())
+}
+
+// Close stops watching SIGHUP, closes the file and waits for the compression of the last rotated file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return nil
+	}
+	rf.closed = true
+	if rf.sig != nil {
+		signal.Stop(rf.sig)
+		close(rf.done)
+	}
+	err := errors.Join(rf.closeFile(), rf.waitCompression(), rf.rotateErr)
+	rf.rotateErr = nil
+	return err
+}
+
+func (rf *RotatingFile) watchSignal() {
+	for {
+		select {
+		case <-rf.sig:
+			rf.Reopen()
+		case <-rf.done:
+			return
+		}
+	}
+}
+
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, rf.opts.Perm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f = f
+	rf.size = info.Size()
+	rf.openedAt = rf.opts.Now()
+	if rf.size > 0 && rf.useModTime {
+		// an existing file belongs to the interval it was last written in.
+		rf.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// closeFile closes the file, if it is open. The file is forgotten even if Close fails,
+// because an *os.File cannot be used after Close.
+func (rf *RotatingFile) closeFile() error {
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
+
+func (rf *RotatingFile) shouldRotate(n int64) bool {
+	if rf.opts.MaxSize > 0 && rf.size > 0 && rf.size+n > rf.opts.MaxSize {
+		return true
+	}
+	if rf.opts.Interval > 0 {
+		return !rf.opts.Now().Truncate(rf.opts.Interval).Equal(rf.openedAt.Truncate(rf.opts.Interval))
+	}
+	return false
+}
+
+// rotate renames the file to path.1, shifting the older backups, and opens a new file.
+// The file is reopened even if it cannot be closed or the backups cannot be shifted, so that logging can continue.
+// If the backups cannot be shifted, the reopened file is the same one, and it is not rotated again
+// until it grows by MaxSize or the next interval starts. If it cannot be reopened, the next Write tries again.
+func (rf *RotatingFile) rotate() error {
+	// path.1 must be compressed before it is shifted. This blocks writes only if rotations outpace compression.
+	err := errors.Join(rf.closeFile(), rf.waitCompression())
+	shiftErr := rf.shiftBackups()
+	if openErr := rf.open(); openErr != nil {
+		return errors.Join(err, shiftErr, openErr)
+	}
+	if shiftErr != nil {
+		rf.size = 0
+		rf.openedAt = rf.opts.Now()
+	}
+	return errors.Join(err, shiftErr)
+}
+
+// waitCompression waits for the compression started by the last rotation, and returns its error.
+func (rf *RotatingFile) waitCompression() error {
+	rf.compression.Wait()
+	err := rf.compressErr
+	rf.compressErr = nil
+	return err
+}
+
+func (rf *RotatingFile) shiftBackups() error {
+	backups, err := rf.backups()
+	if err != nil {
+		return err
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		if rf.opts.MaxBackups > 0 && b.index >= rf.opts.MaxBackups {
+			if err := os.Remove(b.name); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Rename(b.name, rf.backupName(b.index+1, b.compressed)); err != nil {
+			return err
+		}
+	}
+	first := rf.backupName(1, false)
+	if err := os.Rename(rf.path, first); err != nil {
+		return err
+	}
+	if rf.opts.Compress {
+		rf.compression.Add(1)
+		go func() {
+			defer rf.compression.Done()
+			rf.compressErr = gzipFile(first, rf.backupName(1, true), rf.opts.Perm)
+		}()
+	}
+	return nil
+}
+
+type rotatedBackup struct {
+	name       string
+	index      int
+	compressed bool
+}
+
+// backups returns the existing backups, ordered by index.
+func (rf *RotatingFile) backups() ([]rotatedBackup, error) {
+	names, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var backups []rotatedBackup
+	for _, name := range names {
+		suffix := strings.TrimPrefix(name, rf.path+".")
+		compressed := strings.HasSuffix(suffix, ".gz")
+		index, err := strconv.Atoi(strings.TrimSuffix(suffix, ".gz"))
+		if err != nil || index < 1 {
+			continue
+		}
+		backups = append(backups, rotatedBackup{name: name, index: index, compressed: compressed})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].index < backups[j].index })
+	return backups, nil
+}
+
+func (rf *RotatingFile) backupName(index int, compressed bool) string {
+	name := fmt.Sprintf("%s.%d", rf.path, index)
+	if compressed {
+		name += ".gz"
+	}
+	return name
+}
+
+// gzipFile compresses src into dst and removes src.
+func gzipFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,204 @@
+package slogutils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile__MaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path, RotatingFileOptions{
+		MaxSize:    10,
+		MaxBackups: 2,
+		Compress:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		path:           "fourth\n",
+		path + ".1.gz": "third\n",
+		path + ".2.gz": "second\n",
+	}
+	for name, content := range expected {
+		if actual := readLogFile(t, name); actual != content {
+			t.Errorf("%s: expected %q, got %q", name, content, actual)
+		}
+	}
+	if _, err := os.Stat(path + ".3.gz"); !os.IsNotExist(err) {
+		t.Errorf("expected %s.3.gz to be removed, got %v", path, err)
+	}
+}
+
+func TestRotatingFile__Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2023, 8, 15, 23, 59, 0, 0, time.UTC)
+	rf, err := NewRotatingFile(path, RotatingFileOptions{
+		Interval: 24 * time.Hour,
+		Now:      func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	logger := slog.New(NewMiddleware(slog.NewTextHandler, MiddlewareOptions{Writer: rf}))
+	logger.Info("foo")
+	now = now.Add(30 * time.Second)
+	logger.Info("bar")
+	now = now.Add(time.Minute)
+	logger.Info("baz")
+	if actual := readLogFile(t, path+".1"); !strings.Contains(actual, "msg=foo") || !strings.Contains(actual, "msg=bar") {
+		t.Errorf("expected foo and bar in %s.1, got %q", path, actual)
+	}
+	if actual := readLogFile(t, path); !strings.Contains(actual, "msg=baz") || strings.Contains(actual, "msg=bar") {
+		t.Errorf("expected only baz in %s, got %q", path, actual)
+	}
+}
+
+func TestRotatingFile__IntervalExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := NewRotatingFile(path, RotatingFileOptions{
+		Interval: 24 * time.Hour,
+		Now:      func() time.Time { return time.Date(2023, 8, 15, 12, 0, 0, 0, time.UTC) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.Write([]byte("new\n"))
+	if actual := readLogFile(t, path); actual != "old\nnew\n" {
+		t.Errorf("expected the file opened on the clock of Now not to be rotated, got %q", actual)
+	}
+}
+
+func TestRotatingFile__RotateFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "app.log")
+	rf, err := NewRotatingFile(path, RotatingFileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.f.Close()
+	if err := rf.Rotate(); err == nil {
+		t.Error("expected an error for the file that cannot be closed")
+	}
+	if _, err := rf.Write([]byte("first\n")); err != nil {
+		t.Fatalf("expected the rotated file to be writable, got %s", err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Rotate(); err == nil {
+		t.Error("expected an error for the removed directory")
+	}
+	if _, err := rf.Write([]byte("lost\n")); err == nil {
+		t.Error("expected an error while the file cannot be opened")
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rf.Write([]byte("second\n")); err != nil {
+		t.Fatalf("expected the file to be opened again, got %s", err)
+	}
+	if actual := readLogFile(t, path); actual != "second\n" {
+		t.Errorf("expected %q, got %q", "second\n", actual)
+	}
+}
+
+func TestRotatingFile__ShiftFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// a directory that cannot be removed as the oldest backup makes every shift fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "keep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := NewRotatingFile(path, RotatingFileOptions{
+		MaxSize:    10,
+		MaxBackups: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := fmt.Fprintf(rf, "line-%d\n", i); err != nil {
+			t.Fatalf("write %d: expected the line to be written, got %s", i, err)
+		}
+	}
+	if err := rf.Close(); err == nil || !strings.Contains(err.Error(), "app.log.1") {
+		t.Errorf("expected the shift error from Close, got %v", err)
+	}
+	if actual := readLogFile(t, path); actual != "line-0\nline-1\nline-2\nline-3\n" {
+		t.Errorf("expected all lines, got %q", actual)
+	}
+}
+
+func TestRotatingFile__Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(path, RotatingFileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.Write([]byte("first\n"))
+	if err := os.Rename(path, path+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	rf.Write([]byte("second\n"))
+	if actual := readLogFile(t, path); actual != "second\n" {
+		t.Errorf("expected %q, got %q", "second\n", actual)
+	}
+	if actual := readLogFile(t, path+".old"); actual != "first\n" {
+		t.Errorf("expected %q, got %q", "first\n", actual)
+	}
+}
+
+func readLogFile(t *testing.T, name string) string {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
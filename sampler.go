@@ -0,0 +1,184 @@
+package slogutils
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SamplingBudget is the number of records with the same level and message that pass a Sampler in an interval.
+type SamplingBudget struct {
+	// First is the number of records passed at the beginning of each interval.
+	First int
+
+	// Thereafter passes every Thereafter-th record after the first ones. If zero, the rest are dropped.
+	Thereafter int
+}
+
+// SamplerOptions are options for NewSampler.
+type SamplerOptions struct {
+	// Interval is the length of a sampling interval, measured by Now rather than by the time of records.
+	// If zero, one second is used.
+	//
+	// The summaries of an interval are due when it ends, but they are written only when the Middleware asks for them:
+	// with the next handled record, by Middleware.Flush, or every MiddlewareOptions.EmitInterval.
+	// Without EmitInterval, the summaries of a flood that stopped are not written until the next record or Flush.
+	Interval time.Duration
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	// Budget is the budget of levels not found in LevelBudgets.
+	Budget SamplingBudget
+
+	// LevelBudgets is a map of log levels to SamplingBudget.
+	// Like MiddlewareOptions.ModifierFuncs, each level works as a threshold.
+	LevelBudgets map[slog.Level]SamplingBudget
+
+	// NeverSampleLevel is the level at or above which records are never sampled.
+	// If nil, slog.LevelError is used.
+	NeverSampleLevel slog.Leveler
+
+	// SummaryMessage is the message of summary records. If empty, "log records were suppressed by sampling" is used.
+	SummaryMessage string
+}
+
+// Sampler is a RecordProcessor that samples records per level and message,
+// so that a hot path cannot flood the logs.
+// For each message that had suppressed records in an interval, it emits a summary record
+// with the attributes of the first suppressed record, including those added by WithAttrs and WithGroup.
+// The Middleware passes summaries through the RecordProcessors after the Sampler, such as Redact.
+type Sampler struct {
+	mu          sync.Mutex
+	opts        SamplerOptions
+	levels      []slog.Level
+	windowStart time.Time
+	counters    map[samplingKey]*samplingCounter
+	summaries   []slog.Record
+}
+
+type samplingKey struct {
+	level   slog.Level
+	message string
+}
+
+type samplingCounter struct {
+	seen       int
+	suppressed int
+	first      slog.Record
+}
+
+// NewSampler returns a new Sampler.
+func NewSampler(opts SamplerOptions) *Sampler {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.NeverSampleLevel == nil {
+		opts.NeverSampleLevel = slog.LevelError
+	}
+	if opts.SummaryMessage == "" {
+		opts.SummaryMessage = "log records were suppressed by sampling"
+	}
+	levels := make([]slog.Level, 0, len(opts.LevelBudgets))
+	for l := range opts.LevelBudgets {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	return &Sampler{
+		opts:     opts,
+		levels:   levels,
+		counters: make(map[samplingKey]*samplingCounter),
+	}
+}
+
+// ProcessRecord implements RecordProcessor.
+func (s *Sampler) ProcessRecord(_ context.Context, r slog.Record) (slog.Record, bool, error) {
+	if r.Level >= s.opts.NeverSampleLevel.Level() {
+		return r, true, nil
+	}
+	now := s.opts.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+	}
+	if now.Sub(s.windowStart) >= s.opts.Interval {
+		s.rotate(now)
+	}
+	key := samplingKey{level: r.Level, message: r.Message}
+	c, ok := s.counters[key]
+	if !ok {
+		c = &samplingCounter{}
+		s.counters[key] = c
+	}
+	c.seen++
+	budget := s.budget(r.Level)
+	if c.seen <= budget.First {
+		return r, true, nil
+	}
+	if budget.Thereafter > 0 && (c.seen-budget.First)%budget.Thereafter == 0 {
+		return r, true, nil
+	}
+	if c.suppressed == 0 {
+		c.first = r.Clone()
+	}
+	c.suppressed++
+	return r, false, nil
+}
+
+// EmitRecords implements RecordEmitter.
+func (s *Sampler) EmitRecords(flush bool) []slog.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.opts.Now()
+	if flush || (!s.windowStart.IsZero() && now.Sub(s.windowStart) >= s.opts.Interval) {
+		s.rotate(now)
+	}
+	summaries := s.summaries
+	s.summaries = nil
+	return summaries
+}
+
+func (s *Sampler) budget(l slog.Level) SamplingBudget {
+	i := sort.Search(len(s.levels), func(i int) bool {
+		return s.levels[i] > l
+	})
+	if i == 0 {
+		return s.opts.Budget
+	}
+	return s.opts.LevelBudgets[s.levels[i-1]]
+}
+
+// rotate starts a new interval at now, and queues the summaries of the interval that ended.
+func (s *Sampler) rotate(now time.Time) {
+	keys := make([]samplingKey, 0, len(s.counters))
+	for key, c := range s.counters {
+		if c.suppressed > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].message < keys[j].message
+	})
+	for _, key := range keys {
+		c := s.counters[key]
+		summary := slog.NewRecord(now, key.level, s.opts.SummaryMessage, c.first.PC)
+		summary.AddAttrs(recordAttrs(c.first)...)
+		summary.AddAttrs(
+			slog.String("sampled_message", key.message),
+			slog.Int("suppressed", c.suppressed),
+			slog.Duration("interval", s.opts.Interval),
+		)
+		s.summaries = append(s.summaries, summary)
+	}
+	s.counters = make(map[samplingKey]*samplingCounter)
+	s.windowStart = now
+}
@@ -0,0 +1,208 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	now := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+	middleware := NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			RecordProcessors: []RecordProcessor{
+				NewSampler(SamplerOptions{
+					Interval: time.Minute,
+					Now:      func() time.Time { return now },
+					Budget:   SamplingBudget{First: 2, Thereafter: 3},
+					LevelBudgets: map[slog.Level]SamplingBudget{
+						slog.LevelWarn: {First: 1},
+					},
+				}),
+			},
+			Writer: buf,
+			HandlerOptions: &slog.HandlerOptions{
+				Level: slog.LevelInfo,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey && len(groups) == 0 {
+						return slog.Attr{}
+					}
+					return a
+				},
+			},
+		},
+	)
+	ctx := context.Background()
+	handle := func(level slog.Level, msg string) {
+		t.Helper()
+		if err := middleware.Handle(ctx, slog.NewRecord(time.Time{}, level, msg, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		handle(slog.LevelInfo, "hot")
+		handle(slog.LevelWarn, "retry")
+		handle(slog.LevelError, "failed")
+	}
+	now = now.Add(time.Minute)
+	handle(slog.LevelInfo, "cold")
+	expected := []string{
+		`{"level":"INFO","msg":"hot"}`,
+		`{"level":"WARN","msg":"retry"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"INFO","msg":"hot"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"INFO","msg":"hot"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"INFO","msg":"hot"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"ERROR","msg":"failed"}`,
+		`{"level":"INFO","msg":"log records were suppressed by sampling","sampled_message":"hot","suppressed":6,"interval":60000000000}`,
+		`{"level":"WARN","msg":"log records were suppressed by sampling","sampled_message":"retry","suppressed":9,"interval":60000000000}`,
+		`{"level":"INFO","msg":"cold"}`,
+	}
+	actual := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(expected) != len(actual) {
+		t.Fatalf("expected %d lines, got %d lines:\n%s", len(expected), len(actual), buf.String())
+	}
+	for i := range expected {
+		var actualObj, expectedObj map[string]interface{}
+		if err := json.Unmarshal([]byte(actual[i]), &actualObj); err != nil {
+			t.Fatalf("failed to unmarshal actual %q: %s", actual[i], err)
+		}
+		if err := json.Unmarshal([]byte(expected[i]), &expectedObj); err != nil {
+			t.Fatalf("failed to unmarshal expected %q: %s", expected[i], err)
+		}
+		if !jsonEqual(actualObj, expectedObj) {
+			t.Errorf("expected %q, got %q", expected[i], actual[i])
+		}
+	}
+
+	buf.Reset()
+	handle(slog.LevelInfo, "cold")
+	handle(slog.LevelInfo, "cold")
+	if err := middleware.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"sampled_message":"cold","suppressed":1`) {
+		t.Errorf("expected summary on flush, got %q", buf.String())
+	}
+}
+
+func TestSampler__SummaryContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		slog.NewTextHandler,
+		MiddlewareOptions{
+			RecordProcessors: []RecordProcessor{
+				NewSampler(SamplerOptions{
+					Interval: time.Minute,
+					Budget:   SamplingBudget{First: 1},
+				}),
+				RedactSecrets(),
+			},
+			Writer: buf,
+			HandlerOptions: &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey && len(groups) == 0 {
+						return slog.Attr{}
+					}
+					return a
+				},
+			},
+		},
+	)
+	logger := slog.New(middleware).With("app", "test").WithGroup("req")
+	for i := 0; i < 3; i++ {
+		logger.Info("login alice@example.com", "id", i)
+	}
+	if err := middleware.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`level=INFO msg="login [REDACTED]" app=test req.id=0`,
+		`level=INFO msg="log records were suppressed by sampling" app=test req.id=1 sampled_message="login [REDACTED]" suppressed=2 interval=1m0s`,
+	}
+	actual := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d lines, got %d lines:\n%s", len(expected), len(actual), buf.String())
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], actual[i])
+		}
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that can be written and read concurrently.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSampler__EmitInterval(t *testing.T) {
+	buf := new(lockedBuffer)
+	var mu sync.Mutex
+	now := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+	middleware := NewMiddleware(
+		slog.NewTextHandler,
+		MiddlewareOptions{
+			RecordTransformerFuncs: []RecordTransformerFunc{
+				FixedTime(time.Time{}),
+			},
+			RecordProcessors: []RecordProcessor{
+				NewSampler(SamplerOptions{
+					Interval: time.Minute,
+					Now: func() time.Time {
+						mu.Lock()
+						defer mu.Unlock()
+						return now
+					},
+					Budget: SamplingBudget{First: 1},
+				}),
+			},
+			EmitInterval: time.Millisecond,
+			Writer:       buf,
+		},
+	)
+	defer middleware.Close()
+	logger := slog.New(middleware)
+	for i := 0; i < 3; i++ {
+		logger.Info("hot")
+	}
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	expected := `level=INFO msg="log records were suppressed by sampling" sampled_message=hot suppressed=2 interval=1m0s`
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), expected) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s without another record, got %q", expected, buf.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,100 @@
+package slogutilstest
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashiike/slogutils"
+)
+
+// GoldenTime is the time of every record written by Golden.
+var GoldenTime = time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+
+// updateGolden reports whether the -update flag is set. The flag is not defined by this package,
+// so that it does not conflict with the flag of the test package; if it is not defined, it is false.
+func updateGolden() bool {
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	update, _ := strconv.ParseBool(f.Value.String())
+	return update
+}
+
+// Golden runs run with a logger that writes to a Middleware created by f and opts,
+// and compares the output with the golden file at path, such as "testdata/example.golden".
+// If the test package defines an -update flag, such as by
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+// run the test with -update to write the output to the golden file instead.
+//
+// The output is deterministic: every record has GoldenTime as its time and no source,
+// by slogutils.FixedTime and slogutils.ZeroPC applied after opts.RecordTransformerFuncs, and colors are disabled
+// unless opts.ColorMode is slogutils.ColorModeAlways.
+func Golden[H slog.Handler](t testing.TB, path string, f func(io.Writer, *slog.HandlerOptions) H, opts slogutils.MiddlewareOptions, run func(*slog.Logger)) {
+	t.Helper()
+	golden(t, path, f, opts, run, updateGolden())
+}
+
+func golden[H slog.Handler](t testing.TB, path string, f func(io.Writer, *slog.HandlerOptions) H, opts slogutils.MiddlewareOptions, run func(*slog.Logger), update bool) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	opts.Writer = buf
+	if opts.ColorMode != slogutils.ColorModeAlways {
+		opts.ColorMode = slogutils.ColorModeNever
+	}
+	opts.RecordTransformerFuncs = append(opts.RecordTransformerFuncs[:len(opts.RecordTransformerFuncs):len(opts.RecordTransformerFuncs)],
+		slogutils.FixedTime(GoldenTime),
+		slogutils.ZeroPC(),
+	)
+	m := slogutils.NewMiddleware(f, opts)
+	run(slog.New(m))
+	if err := m.Close(); err != nil {
+		t.Fatalf("failed to close the middleware: %s", err)
+	}
+	actual := buf.String()
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create the directory of golden file %s: %s", path, err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatalf("failed to write golden file %s: %s", path, err)
+		}
+		return
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s, run with -update to create it: %s", path, err)
+	}
+	if expected := string(b); actual != expected {
+		t.Errorf("output does not match golden file %s, run with -update to update it:\n%s", path, diffLines(expected, actual))
+	}
+}
+
+// diffLines describes the first line at which expected and actual differ.
+func diffLines(expected, actual string) string {
+	e := strings.Split(expected, "\n")
+	a := strings.Split(actual, "\n")
+	for i := 0; i < len(e) || i < len(a); i++ {
+		var el, al string
+		if i < len(e) {
+			el = e[i]
+		}
+		if i < len(a) {
+			al = a[i]
+		}
+		if i >= len(e) || i >= len(a) || el != al {
+			return "line " + strconv.Itoa(i+1) + ":\n  expected: " + strconv.Quote(el) + "\n  actual:   " + strconv.Quote(al)
+		}
+	}
+	return ""
+}
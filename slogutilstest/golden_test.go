@@ -0,0 +1,55 @@
+package slogutilstest
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mashiike/slogutils"
+)
+
+// update is read by Golden through flag.Lookup, so that testdata/example.golden is regenerated by go test -update.
+var update = flag.Bool("update", false, "update golden files")
+
+func logGoldenExample(logger *slog.Logger) {
+	ctx := slogutils.With(context.Background(), slog.Int64("request_id", 12))
+	logger.InfoContext(ctx, "hello", "user", "alice")
+	logger.WithGroup("db").WarnContext(ctx, "slow query", slog.Int("rows", 3))
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, "testdata/example.golden", slog.NewJSONHandler, slogutils.MiddlewareOptions{
+		HandlerOptions: &slog.HandlerOptions{AddSource: true},
+	}, logGoldenExample)
+}
+
+func TestGolden__Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "update.golden")
+	golden(t, path, slog.NewTextHandler, slogutils.MiddlewareOptions{}, logGoldenExample, true)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %s", err)
+	}
+	expected := `time=2023-08-15T00:00:00.000Z level=INFO msg=hello request_id=12 user=alice` + "\n" +
+		`time=2023-08-15T00:00:00.000Z level=WARN msg="slow query" db.request_id=12 db.rows=3` + "\n"
+	if string(b) != expected {
+		t.Errorf("expected %q, got %q", expected, string(b))
+	}
+}
+
+func TestGolden__Mismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mismatch.golden")
+	os.WriteFile(path, []byte("{\"msg\":\"hello\"}\n"), 0644)
+	ft := &fakeT{}
+	golden(ft, path, slog.NewJSONHandler, slogutils.MiddlewareOptions{}, logGoldenExample, false)
+	if len(ft.errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", ft.errors)
+	}
+	if !strings.Contains(ft.errors[0], "line 1:\n  expected: \"{\\\"msg\\\":\\\"hello\\\"}\"") {
+		t.Errorf("unexpected error: %s", ft.errors[0])
+	}
+}
@@ -0,0 +1,314 @@
+// Package slogutilstest provides a slog.Handler that records log records for tests, and assertions on them.
+package slogutilstest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mashiike/slogutils"
+)
+
+// Record is a captured log record with its attributes resolved.
+// The attributes include those added by WithAttrs and WithGroup, and the attributes stored in the context by slogutils.With,
+// nested in their groups.
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	PC      uintptr
+	Attrs   []slog.Attr
+}
+
+// Value returns the value of the attribute at the dotted path, such as "request_id" or "http.method".
+func (r Record) Value(path string) (slog.Value, bool) {
+	attrs := r.Attrs
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		found := false
+		for j := len(attrs) - 1; j >= 0; j-- {
+			a := attrs[j]
+			if a.Key != key {
+				continue
+			}
+			if i == len(keys)-1 {
+				return a.Value, true
+			}
+			if a.Value.Kind() == slog.KindGroup {
+				attrs = a.Value.Group()
+				found = true
+				break
+			}
+		}
+		if !found {
+			return slog.Value{}, false
+		}
+	}
+	return slog.Value{}, false
+}
+
+// String returns the record in a logfmt-like form with dotted keys, for failure messages.
+func (r Record) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "level=%s msg=%q", r.Level, r.Message)
+	var appendAttrs func(prefix string, attrs []slog.Attr)
+	appendAttrs = func(prefix string, attrs []slog.Attr) {
+		for _, a := range attrs {
+			if a.Value.Kind() == slog.KindGroup {
+				appendAttrs(prefix+a.Key+".", a.Value.Group())
+				continue
+			}
+			fmt.Fprintf(&b, " %s%s=%v", prefix, a.Key, a.Value)
+		}
+	}
+	appendAttrs("", r.Attrs)
+	return b.String()
+}
+
+// Recorder is a slog.Handler that records log records in memory.
+// It is a slogutils.Middleware, so MiddlewareOptions such as RecordTransformerFuncs and ContextExtractors are applied
+// before records are captured. HandlerOptions.Level filters the records; ReplaceAttr and Writer are ignored.
+type Recorder struct {
+	*slogutils.Middleware[*recordingHandler]
+	store *recordStore
+}
+
+type recordStore struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewRecorder returns a new Recorder. If opts.HandlerOptions has no Level, slog.LevelDebug is used,
+// so that records of all the standard levels are captured and Middleware.MinLevel reports the level the Recorder uses.
+func NewRecorder(opts slogutils.MiddlewareOptions) *Recorder {
+	store := &recordStore{}
+	ho := slog.HandlerOptions{}
+	if opts.HandlerOptions != nil {
+		ho = *opts.HandlerOptions
+	}
+	if ho.Level == nil {
+		ho.Level = slog.LevelDebug
+	}
+	opts.HandlerOptions = &ho
+	opts.Writer = io.Discard
+	opts.ColorMode = slogutils.ColorModeNever
+	m := slogutils.NewMiddleware(func(_ io.Writer, ho *slog.HandlerOptions) *recordingHandler {
+		return &recordingHandler{store: store, level: ho.Level}
+	}, opts)
+	return &Recorder{Middleware: m, store: store}
+}
+
+// Logger returns a *slog.Logger that writes to the Recorder.
+func (r *Recorder) Logger() *slog.Logger {
+	return slog.New(r)
+}
+
+// Records returns the captured records in order.
+func (r *Recorder) Records() []Record {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return append([]Record(nil), r.store.records...)
+}
+
+// Filter returns the captured records that match all matchers.
+func (r *Recorder) Filter(matchers ...Matcher) []Record {
+	var records []Record
+	for _, record := range r.Records() {
+		if matchAll(record, matchers) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Reset discards the captured records.
+func (r *Recorder) Reset() {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.records = nil
+}
+
+// recordingHandler is the handler inside the Middleware of a Recorder.
+// The Middleware keeps the attributes and groups itself, so WithAttrs and WithGroup are never called with anything to keep.
+type recordingHandler struct {
+	store *recordStore
+	level slog.Leveler
+}
+
+// Enabled treats a nil level as slog.LevelInfo, as Middleware.MinLevel and the handlers of log/slog do.
+func (h *recordingHandler) Enabled(_ context.Context, l slog.Level) bool {
+	if h.level == nil {
+		return l >= slog.LevelInfo
+	}
+	return l >= h.level.Level()
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	record := Record{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		PC:      r.PC,
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		record.Attrs = append(record.Attrs, resolveAttr(a)...)
+		return true
+	})
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.records = append(h.store.records, record)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *recordingHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+// resolveAttr resolves a and the attributes of its groups, and inlines groups with an empty key, as handlers do.
+func resolveAttr(a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		if a.Equal(slog.Attr{}) {
+			return nil
+		}
+		return []slog.Attr{a}
+	}
+	var attrs []slog.Attr
+	for _, ga := range a.Value.Group() {
+		attrs = append(attrs, resolveAttr(ga)...)
+	}
+	if a.Key == "" {
+		return attrs
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return []slog.Attr{{Key: a.Key, Value: slog.GroupValue(attrs...)}}
+}
+
+// Matcher matches captured records.
+type Matcher interface {
+	Match(Record) bool
+	String() string
+}
+
+type matcher struct {
+	desc string
+	f    func(Record) bool
+}
+
+func (m matcher) Match(r Record) bool { return m.f(r) }
+func (m matcher) String() string      { return m.desc }
+
+// MatchFunc returns a Matcher that matches records for which f returns true, described by desc in failure messages.
+func MatchFunc(desc string, f func(Record) bool) Matcher {
+	return matcher{desc: desc, f: f}
+}
+
+// AtLevel matches records at level l.
+func AtLevel(l slog.Level) Matcher {
+	return MatchFunc("level="+l.String(), func(r Record) bool { return r.Level == l })
+}
+
+// Message matches records with the message msg.
+func Message(msg string) Matcher {
+	return MatchFunc(fmt.Sprintf("msg=%q", msg), func(r Record) bool { return r.Message == msg })
+}
+
+// MessageContains matches records whose message contains s.
+func MessageContains(s string) Matcher {
+	return MatchFunc(fmt.Sprintf("msg contains %q", s), func(r Record) bool { return strings.Contains(r.Message, s) })
+}
+
+// HasKey matches records with an attribute at the dotted path.
+func HasKey(path string) Matcher {
+	return MatchFunc("has "+path, func(r Record) bool {
+		_, ok := r.Value(path)
+		return ok
+	})
+}
+
+// HasAttr matches records with an attribute at the dotted path whose value equals value.
+// Values are compared as slog.Values, so HasAttr("request_id", 12) matches slog.Int64("request_id", 12).
+func HasAttr(path string, value any) Matcher {
+	expected := slog.AnyValue(value).Resolve()
+	return MatchFunc(fmt.Sprintf("%s=%v", path, expected), func(r Record) bool {
+		v, ok := r.Value(path)
+		if !ok {
+			return false
+		}
+		if v.Kind() == slog.KindAny || expected.Kind() == slog.KindAny {
+			return v.Kind() == expected.Kind() && reflect.DeepEqual(v.Any(), expected.Any())
+		}
+		return v.Equal(expected)
+	})
+}
+
+func matchAll(r Record, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m.Match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// describe returns " with " and the descriptions of matchers, or "" if there are none.
+func describe(matchers []Matcher) string {
+	if len(matchers) == 0 {
+		return ""
+	}
+	descs := make([]string, len(matchers))
+	for i, m := range matchers {
+		descs[i] = m.String()
+	}
+	return " with " + strings.Join(descs, " ")
+}
+
+func dump(records []Record) string {
+	if len(records) == 0 {
+		return "  (no records)"
+	}
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = "  " + r.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AssertRecord reports an error unless rec captured a record that matches all matchers, and returns the first such record.
+func AssertRecord(t testing.TB, rec *Recorder, matchers ...Matcher) Record {
+	t.Helper()
+	records := rec.Filter(matchers...)
+	if len(records) == 0 {
+		t.Errorf("expected a record%s, got:\n%s", describe(matchers), dump(rec.Records()))
+		return Record{}
+	}
+	return records[0]
+}
+
+// AssertNoRecord reports an error if rec captured a record that matches all matchers.
+func AssertNoRecord(t testing.TB, rec *Recorder, matchers ...Matcher) {
+	t.Helper()
+	if records := rec.Filter(matchers...); len(records) > 0 {
+		t.Errorf("expected no record%s, got:\n%s", describe(matchers), dump(records))
+	}
+}
+
+// AssertCount reports an error unless rec captured exactly n records that match all matchers.
+func AssertCount(t testing.TB, rec *Recorder, n int, matchers ...Matcher) {
+	t.Helper()
+	if records := rec.Filter(matchers...); len(records) != n {
+		t.Errorf("expected %d records%s, got %d:\n%s", n, describe(matchers), len(records), dump(rec.Records()))
+	}
+}
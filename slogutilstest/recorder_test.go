@@ -0,0 +1,99 @@
+package slogutilstest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mashiike/slogutils"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(slogutils.MiddlewareOptions{
+		RecordTransformerFuncs: []slogutils.RecordTransformerFunc{
+			slogutils.DropAttrs("req.password"),
+		},
+	})
+	logger := rec.Logger().With("app", "test").WithGroup("req")
+	ctx := slogutils.With(context.Background(), slog.Int64("request_id", 12))
+	logger.DebugContext(ctx, "start", "path", "/")
+	logger.WarnContext(ctx, "slow", slog.Group("db", slog.Duration("elapsed", 0)), "password", "secret")
+
+	records := rec.Records()
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if s := records[0].String(); s != `level=DEBUG msg="start" app=test req.request_id=12 req.path=/` {
+		t.Errorf("unexpected record: %s", s)
+	}
+	r := AssertRecord(t, rec, AtLevel(slog.LevelWarn), HasAttr("req.request_id", 12), HasKey("req.db.elapsed"))
+	if r.Message != "slow" {
+		t.Errorf("expected slow, got %s", r.Message)
+	}
+	AssertNoRecord(t, rec, HasKey("req.password"))
+	AssertCount(t, rec, 2, HasAttr("app", "test"))
+	AssertCount(t, rec, 1, MessageContains("sl"))
+
+	rec.Reset()
+	AssertCount(t, rec, 0)
+}
+
+func TestRecorder__Level(t *testing.T) {
+	rec := NewRecorder(slogutils.MiddlewareOptions{
+		HandlerOptions: &slog.HandlerOptions{Level: slog.LevelInfo},
+	})
+	logger := rec.Logger()
+	logger.Debug("dropped")
+	logger.Info("kept")
+	AssertNoRecord(t, rec, Message("dropped"))
+	AssertRecord(t, rec, Message("kept"))
+}
+
+func TestRecorder__MinLevel(t *testing.T) {
+	rec := NewRecorder(slogutils.MiddlewareOptions{})
+	if l := rec.MinLevel(); l != slog.LevelDebug {
+		t.Errorf("expected MinLevel %v, got %v", slog.LevelDebug, l)
+	}
+	slogutils.NewLevelControl(rec, slogutils.LevelControlOptions{}).Quiet()
+	logger := rec.Logger()
+	logger.Debug("dropped")
+	logger.Info("kept")
+	AssertNoRecord(t, rec, Message("dropped"))
+	AssertRecord(t, rec, Message("kept"))
+}
+
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions__Failure(t *testing.T) {
+	rec := NewRecorder(slogutils.MiddlewareOptions{})
+	rec.Logger().Info("hello", "request_id", 13)
+
+	ft := &fakeT{}
+	AssertRecord(ft, rec, AtLevel(slog.LevelWarn), HasAttr("request_id", 12))
+	AssertNoRecord(ft, rec, Message("hello"))
+	AssertCount(ft, rec, 2)
+	if len(ft.errors) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(ft.errors), ft.errors)
+	}
+	expected := "expected a record with level=WARN request_id=12, got:\n  level=INFO msg=\"hello\" request_id=13"
+	if ft.errors[0] != expected {
+		t.Errorf("expected %q, got %q", expected, ft.errors[0])
+	}
+	if !strings.Contains(ft.errors[1], `expected no record with msg="hello"`) {
+		t.Errorf("unexpected error: %s", ft.errors[1])
+	}
+	if !strings.Contains(ft.errors[2], "expected 2 records, got 1") {
+		t.Errorf("unexpected error: %s", ft.errors[2])
+	}
+}
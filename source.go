@@ -0,0 +1,154 @@
+package slogutils
+
+import (
+	"log/slog"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// SourcePath decides how AddSource writes the file of the source.
+type SourcePath int
+
+const (
+	// SourcePathModule writes the file relative to the main module, such as "db/db.go",
+	// and files of other modules with their package path, such as "github.com/acme/lib/client.go".
+	// Files of the main package, whose package path is not recorded, are written by their base name.
+	SourcePathModule SourcePath = iota
+	// SourcePathBase writes the base name of the file, such as "db.go".
+	SourcePathBase
+	// SourcePathFull writes the file as recorded at build time, such as "/home/ci/build/app/db/db.go".
+	SourcePathFull
+)
+
+// SourceOptions are options for AddSource.
+type SourceOptions struct {
+	// Key is the key of the source attribute. If empty, slog.SourceKey is used.
+	Key string
+
+	// Path decides how the file is written.
+	Path SourcePath
+
+	// Function adds the function name. With SourcePathFull it is the full name, such as "github.com/acme/app/db.(*DB).Query",
+	// and otherwise it is qualified by the package name only, such as "db.(*DB).Query".
+	Function bool
+
+	// Skip is the number of frames to skip above the caller of the logger, for loggers called through wrapper functions.
+	Skip int
+
+	// SkipPackages are package paths, such as "github.com/acme/logwrap", whose frames are skipped after Skip.
+	SkipPackages []string
+
+	// ModulePath is the path of the main module for SourcePathModule.
+	// If empty, the path in the build information of the binary is used.
+	ModulePath string
+}
+
+// AddSource returns a RecordTransformerFunc that resolves the PC of a slog.Record into a source attribute,
+// written in the shape the handler uses for HandlerOptions.AddSource, with the file shortened by SourceOptions.Path.
+// Use it instead of HandlerOptions.AddSource, so that each sink can shorten the source in its own way.
+//
+// With Skip or SkipPackages, the record's PC is also moved to the selected frame.
+// Skipping frames needs the stack of the logging goroutine, so it works only while the record is handled
+// in the call that logged it.
+func AddSource(opts SourceOptions) RecordTransformerFunc {
+	if opts.Key == "" {
+		opts.Key = slog.SourceKey
+	}
+	if opts.ModulePath == "" {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			opts.ModulePath = bi.Main.Path
+		}
+	}
+	return func(r slog.Record) slog.Record {
+		pc := r.PC
+		if opts.Skip > 0 || len(opts.SkipPackages) > 0 {
+			pc = skipFrames(pc, opts.Skip, opts.SkipPackages)
+		}
+		if pc == 0 {
+			return r
+		}
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		src := &slog.Source{
+			File: sourceFile(frame, opts),
+			Line: frame.Line,
+		}
+		if opts.Function {
+			src.Function = unescapeFunction(frame.Function)
+			if opts.Path != SourcePathFull {
+				src.Function = path.Base(src.Function)
+			}
+		}
+		c := r.Clone()
+		c.PC = pc
+		c.AddAttrs(slog.Any(opts.Key, src))
+		return c
+	}
+}
+
+// skipFrames finds pc in the stack of the current goroutine, and returns the PC skip frames above it,
+// then above any frames of skipPackages. If pc is not in the stack, it is returned as is.
+func skipFrames(pc uintptr, skip int, skipPackages []string) uintptr {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for i := 0; i < n; i++ {
+		if pcs[i] != pc {
+			continue
+		}
+		for j := i + skip; j < n; j++ {
+			frame, _ := runtime.CallersFrames(pcs[j : j+1]).Next()
+			if !inPackages(frame.Function, skipPackages) {
+				return pcs[j]
+			}
+		}
+		return pc
+	}
+	return pc
+}
+
+func inPackages(function string, packages []string) bool {
+	pkg := functionPackage(function)
+	for _, p := range packages {
+		if pkg == p {
+			return true
+		}
+	}
+	return false
+}
+
+// functionPackage returns the package path of a function name such as "github.com/acme/app/db.(*DB).Query".
+// The runtime escapes the dots in the last element of the path, as in "gopkg.in/yaml%2ev3.Unmarshal", and they are unescaped.
+func functionPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/') + 1
+	if dot := strings.IndexByte(function[slash:], '.'); dot >= 0 {
+		function = function[:slash+dot]
+	}
+	return unescapeFunction(function)
+}
+
+// unescapeFunction unescapes the dots escaped by the runtime in a function name or package path.
+func unescapeFunction(s string) string {
+	return strings.ReplaceAll(s, "%2e", ".")
+}
+
+func sourceFile(frame runtime.Frame, opts SourceOptions) string {
+	switch opts.Path {
+	case SourcePathFull:
+		return frame.File
+	case SourcePathBase:
+		return filepath.Base(frame.File)
+	}
+	base := filepath.Base(frame.File)
+	pkg := functionPackage(frame.Function)
+	switch {
+	case pkg == "main" || pkg == "":
+		return base
+	case opts.ModulePath != "" && pkg == opts.ModulePath:
+		return base
+	case opts.ModulePath != "" && strings.HasPrefix(pkg, opts.ModulePath+"/"):
+		return strings.TrimPrefix(pkg, opts.ModulePath+"/") + "/" + base
+	}
+	return pkg + "/" + base
+}
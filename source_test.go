@@ -0,0 +1,158 @@
+package slogutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logHere logs hello with logger and returns the line of the call.
+func logHere(logger *slog.Logger) int {
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("hello")
+	return line + 1
+}
+
+// logThroughWrapper logs hello with logger through a wrapper function, and returns the line of the call to the wrapper.
+func logThroughWrapper(logger *slog.Logger) int {
+	_, _, line, _ := runtime.Caller(0)
+	infoWrapper(logger, "hello")
+	return line + 1
+}
+
+func infoWrapper(logger *slog.Logger, msg string) {
+	logger.Info(msg)
+}
+
+func TestAddSource(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     SourceOptions
+		log      func(*slog.Logger) int
+		expected string
+	}{
+		{"module", SourceOptions{ModulePath: "github.com/mashiike/slogutils"}, logHere, "source_test.go:%d"},
+		{"parent module", SourceOptions{ModulePath: "github.com/mashiike"}, logHere, "slogutils/source_test.go:%d"},
+		{"other module", SourceOptions{ModulePath: "example.com/app"}, logHere, "github.com/mashiike/slogutils/source_test.go:%d"},
+		{"base", SourceOptions{Path: SourcePathBase}, logHere, "source_test.go:%d"},
+		{"key", SourceOptions{Path: SourcePathBase, Key: "caller"}, logHere, "source_test.go:%d"},
+		{"skip", SourceOptions{Path: SourcePathBase, Skip: 1}, logThroughWrapper, "source_test.go:%d"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			logger := slog.New(NewMiddleware(slog.NewTextHandler, MiddlewareOptions{
+				RecordTransformerFuncs: []RecordTransformerFunc{
+					FixedTime(time.Time{}),
+					AddSource(c.opts),
+				},
+				Writer: buf,
+			}))
+			line := c.log(logger)
+			key := c.opts.Key
+			if key == "" {
+				key = slog.SourceKey
+			}
+			expected := "level=INFO msg=hello " + key + "=" + fmt.Sprintf(c.expected, line)
+			if actual := strings.TrimSpace(buf.String()); actual != expected {
+				t.Errorf("expected %s, got %s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestAddSource__Function(t *testing.T) {
+	cases := []struct {
+		opts     SourceOptions
+		file     string
+		function string
+	}{
+		{SourceOptions{Function: true}, "source_test.go", "slogutils.logHere"},
+		{SourceOptions{Path: SourcePathFull, Function: true}, "/source_test.go", "github.com/mashiike/slogutils.logHere"},
+		{SourceOptions{Path: SourcePathBase, SkipPackages: []string{"github.com/mashiike/slogutils"}, Function: true}, "testing.go", "testing.tRunner"},
+	}
+	for _, c := range cases {
+		t.Run(c.function, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			c.opts.ModulePath = "github.com/mashiike/slogutils"
+			logger := slog.New(NewMiddleware(slog.NewJSONHandler, MiddlewareOptions{
+				RecordTransformerFuncs: []RecordTransformerFunc{
+					AddSource(c.opts),
+				},
+				Writer: buf,
+			}))
+			logHere(logger)
+			var v struct {
+				Source slog.Source `json:"source"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !strings.HasSuffix(v.Source.File, c.file) {
+				t.Errorf("expected file ending with %s, got %s", c.file, v.Source.File)
+			}
+			if v.Source.Function != c.function {
+				t.Errorf("expected function %s, got %s", c.function, v.Source.Function)
+			}
+		})
+	}
+}
+
+func TestAddSource__NoPC(t *testing.T) {
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "hello", 0)
+	if r = AddSource(SourceOptions{})(r); r.NumAttrs() != 0 {
+		t.Errorf("expected no attributes, got %d", r.NumAttrs())
+	}
+}
+
+func TestAddSource__ConsoleHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewMiddleware(NewConsoleHandler, MiddlewareOptions{
+		RecordTransformerFuncs: []RecordTransformerFunc{
+			FixedTime(time.Time{}),
+			AddSource(SourceOptions{Path: SourcePathBase, Function: true}),
+		},
+		Writer:    buf,
+		ColorMode: ColorModeNever,
+	}))
+	line := logHere(logger)
+	if expected := fmt.Sprintf("source=source_test.go:%d", line); !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected %s in %s", expected, buf.String())
+	}
+}
+
+func TestFunctionPackage(t *testing.T) {
+	cases := []struct {
+		function string
+		expected string
+	}{
+		{"github.com/acme/app/db.(*DB).Query", "github.com/acme/app/db"},
+		{"gopkg.in/yaml%2ev3.(*Decoder).Decode", "gopkg.in/yaml.v3"},
+		{"main.main", "main"},
+		{"runtime.goexit", "runtime"},
+	}
+	for _, c := range cases {
+		if actual := functionPackage(c.function); actual != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.function, c.expected, actual)
+		}
+	}
+}
+
+func TestSourceFile__EscapedPath(t *testing.T) {
+	frame := runtime.Frame{Function: "example.com/app%2ev2/db.Query", File: "/build/db/db.go"}
+	if actual := sourceFile(frame, SourceOptions{ModulePath: "example.com/app.v2"}); actual != "db/db.go" {
+		t.Errorf("expected db/db.go, got %s", actual)
+	}
+	frame = runtime.Frame{Function: "gopkg.in/yaml%2ev3.Unmarshal", File: "/go/pkg/mod/gopkg.in/yaml.v3@v3.0.1/yaml.go"}
+	if actual := sourceFile(frame, SourceOptions{ModulePath: "example.com/app"}); actual != "gopkg.in/yaml.v3/yaml.go" {
+		t.Errorf("expected gopkg.in/yaml.v3/yaml.go, got %s", actual)
+	}
+	if !inPackages(frame.Function, []string{"gopkg.in/yaml.v3"}) {
+		t.Error("expected gopkg.in/yaml.v3 to match SkipPackages")
+	}
+}
@@ -0,0 +1,89 @@
+package slogutils
+
+import (
+	"log/slog"
+	"time"
+)
+
+const (
+	// TimeFormatEpochMillis formats the time as an integer number of milliseconds since the Unix epoch.
+	TimeFormatEpochMillis = "epoch_millis"
+	// TimeFormatEpochSeconds formats the time as a floating-point number of seconds since the Unix epoch.
+	TimeFormatEpochSeconds = "epoch_seconds"
+)
+
+// SetTime returns a RecordTransformerFunc that sets the time of a slog.Record to the time returned by now,
+// such as the current time of a fake clock in tests.
+func SetTime(now func() time.Time) RecordTransformerFunc {
+	return func(r slog.Record) slog.Record {
+		r.Time = now()
+		return r
+	}
+}
+
+// FixedTime returns a RecordTransformerFunc that sets the time of a slog.Record to t, for deterministic output in examples and tests.
+// If t is the zero time, handlers omit the time.
+func FixedTime(t time.Time) RecordTransformerFunc {
+	return SetTime(func() time.Time { return t })
+}
+
+// TruncateTime returns a RecordTransformerFunc that rounds the time of a slog.Record down to a multiple of d,
+// such as time.Millisecond.
+func TruncateTime(d time.Duration) RecordTransformerFunc {
+	return func(r slog.Record) slog.Record {
+		r.Time = r.Time.Truncate(d)
+		return r
+	}
+}
+
+// UTCTime returns a RecordTransformerFunc that converts the time of a slog.Record to UTC.
+func UTCTime() RecordTransformerFunc {
+	return TimeIn(time.UTC)
+}
+
+// TimeIn returns a RecordTransformerFunc that converts the time of a slog.Record to the location loc,
+// such as a time.FixedZone.
+func TimeIn(loc *time.Location) RecordTransformerFunc {
+	return func(r slog.Record) slog.Record {
+		if !r.Time.IsZero() {
+			r.Time = r.Time.In(loc)
+		}
+		return r
+	}
+}
+
+// FormatTime returns a RecordTransformerFunc that replaces the time of a slog.Record by a time attribute
+// in the given format, such as time.RFC3339, TimeFormatEpochMillis or TimeFormatEpochSeconds.
+// Any other format is a layout for time.Time.Format.
+// The attribute is the first attribute of the record, so it is written after the level and the message.
+// Dedup ignores the attribute when it compares records, unless a later transformer resolves its value.
+// Records without a time are not changed.
+func FormatTime(format string) RecordTransformerFunc {
+	return func(r slog.Record) slog.Record {
+		if r.Time.IsZero() {
+			return r
+		}
+		var v slog.Value
+		switch format {
+		case TimeFormatEpochMillis:
+			v = slog.Int64Value(r.Time.UnixMilli())
+		case TimeFormatEpochSeconds:
+			v = slog.Float64Value(float64(r.Time.UnixNano()) / float64(time.Second))
+		default:
+			v = slog.StringValue(r.Time.Format(format))
+		}
+		attrs := append([]slog.Attr{{Key: slog.TimeKey, Value: slog.AnyValue(formattedTime{v: v})}}, recordAttrs(r)...)
+		r.Time = time.Time{}
+		return withAttrs(r, attrs)
+	}
+}
+
+// formattedTime is the value of the time attribute added by FormatTime, marked so that Dedup can ignore it.
+type formattedTime struct {
+	v slog.Value
+}
+
+// LogValue implements slog.LogValuer.
+func (t formattedTime) LogValue() slog.Value {
+	return t.v
+}
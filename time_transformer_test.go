@@ -0,0 +1,93 @@
+package slogutils
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeTransformers(t *testing.T) {
+	base := time.Date(2023, 8, 15, 9, 30, 15, 123456789, time.FixedZone("JST", 9*60*60))
+	cases := []struct {
+		name        string
+		transformer RecordTransformerFunc
+		expected    string
+	}{
+		{"set time", SetTime(func() time.Time { return base.Add(time.Hour) }), "2023-08-15T10:30:15.123456789+09:00"},
+		{"fixed time", FixedTime(time.Time{}), "0001-01-01T00:00:00Z"},
+		{"truncate", TruncateTime(time.Millisecond), "2023-08-15T09:30:15.123+09:00"},
+		{"utc", UTCTime(), "2023-08-15T00:30:15.123456789Z"},
+		{"time in", TimeIn(time.FixedZone("EST", -5*60*60)), "2023-08-14T19:30:15.123456789-05:00"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := c.transformer(slog.NewRecord(base, slog.LevelInfo, "hello", 0))
+			if actual := r.Time.Format(time.RFC3339Nano); actual != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	base := time.Date(2023, 8, 15, 0, 30, 15, 500000000, time.UTC)
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{time.RFC3339, `level=INFO msg=hello time=2023-08-15T00:30:15Z user=alice`},
+		{TimeFormatEpochMillis, `level=INFO msg=hello time=1692059415500 user=alice`},
+		{TimeFormatEpochSeconds, `level=INFO msg=hello time=1.6920594155e+09 user=alice`},
+		{"15:04", `level=INFO msg=hello time=00:30 user=alice`},
+	}
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			logger := slog.New(NewMiddleware(slog.NewTextHandler, MiddlewareOptions{
+				RecordTransformerFuncs: []RecordTransformerFunc{
+					FixedTime(base),
+					FormatTime(c.format),
+				},
+				Writer: buf,
+			}))
+			logger.Info("hello", "user", "alice")
+			if actual := strings.TrimSpace(buf.String()); actual != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, actual)
+			}
+		})
+	}
+
+	r := FormatTime(time.RFC3339)(slog.NewRecord(time.Time{}, slog.LevelInfo, "hello", 0))
+	if r.NumAttrs() != 0 {
+		t.Errorf("expected a record without time to be unchanged")
+	}
+}
+
+func TestFormatTime__Dedup(t *testing.T) {
+	buf := new(bytes.Buffer)
+	now := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+	logger := slog.New(NewMiddleware(slog.NewTextHandler, MiddlewareOptions{
+		RecordTransformerFuncs: []RecordTransformerFunc{
+			SetTime(func() time.Time {
+				now = now.Add(time.Millisecond)
+				return now
+			}),
+			FormatTime(TimeFormatEpochMillis),
+		},
+		Dedup:  &DedupOptions{},
+		Writer: buf,
+	}))
+	logger.Info("retry")
+	logger.Info("retry")
+	logger.Info("retry")
+	logger.Info("done")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[1], "repeat_count=2") {
+		t.Errorf("expected repeat_count=2, got %s", lines[1])
+	}
+}
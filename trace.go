@@ -0,0 +1,150 @@
+package slogutils
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// TraceIDKey is the key of the trace ID attribute added by TraceExtractor.
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the key of the span ID attribute added by TraceExtractor.
+	SpanIDKey = "span_id"
+)
+
+// SpanContext is the part of a trace span needed to correlate log records with traces.
+// Traceparent implements it; for other tracing libraries such as OpenTelemetry, write a small adapter.
+type SpanContext interface {
+	TraceID() string
+	SpanID() string
+}
+
+// TraceExtractor returns a ContextExtractorFunc that adds the trace ID and span ID of the span returned by f.
+//
+// Example with OpenTelemetry:
+//
+//	TraceExtractor(func(ctx context.Context) (SpanContext, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return otelSpanContext{sc}, sc.IsValid()
+//	})
+func TraceExtractor(f func(context.Context) (SpanContext, bool)) ContextExtractorFunc {
+	return func(ctx context.Context) []slog.Attr {
+		sc, ok := f(ctx)
+		if !ok {
+			return nil
+		}
+		return []slog.Attr{
+			slog.String(TraceIDKey, sc.TraceID()),
+			slog.String(SpanIDKey, sc.SpanID()),
+		}
+	}
+}
+
+// TraceparentExtractor returns a ContextExtractorFunc that adds the trace ID and span ID
+// of the Traceparent stored in the context by WithTraceparent.
+func TraceparentExtractor() ContextExtractorFunc {
+	return TraceExtractor(func(ctx context.Context) (SpanContext, bool) {
+		return TraceparentFromContext(ctx)
+	})
+}
+
+// Traceparent is a W3C Trace Context traceparent, such as "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+type Traceparent struct {
+	version byte
+	traceID [16]byte
+	spanID  [8]byte
+	flags   byte
+}
+
+// ErrInvalidTraceparent is returned by ParseTraceparent for malformed values.
+var ErrInvalidTraceparent = errors.New("slogutils: invalid traceparent")
+
+// ParseTraceparent parses the value of a traceparent header.
+func ParseTraceparent(s string) (Traceparent, error) {
+	var tp Traceparent
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 {
+		return tp, ErrInvalidTraceparent
+	}
+	version, err := decodeHex(parts[0], 1)
+	if err != nil || version[0] == 0xff {
+		return tp, ErrInvalidTraceparent
+	}
+	// version 00 has exactly four fields; later versions may append more.
+	if version[0] == 0 && len(parts) != 4 {
+		return tp, ErrInvalidTraceparent
+	}
+	traceID, err := decodeHex(parts[1], 16)
+	if err != nil || isZero(traceID) {
+		return tp, ErrInvalidTraceparent
+	}
+	spanID, err := decodeHex(parts[2], 8)
+	if err != nil || isZero(spanID) {
+		return tp, ErrInvalidTraceparent
+	}
+	flags, err := decodeHex(parts[3], 1)
+	if err != nil {
+		return tp, ErrInvalidTraceparent
+	}
+	tp.version = version[0]
+	copy(tp.traceID[:], traceID)
+	copy(tp.spanID[:], spanID)
+	tp.flags = flags[0]
+	return tp, nil
+}
+
+// TraceID returns the trace ID as 32 lowercase hex characters.
+func (tp Traceparent) TraceID() string {
+	return hex.EncodeToString(tp.traceID[:])
+}
+
+// SpanID returns the parent span ID as 16 lowercase hex characters.
+func (tp Traceparent) SpanID() string {
+	return hex.EncodeToString(tp.spanID[:])
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tp Traceparent) Sampled() bool {
+	return tp.flags&0x01 != 0
+}
+
+// String returns tp in the traceparent header format.
+func (tp Traceparent) String() string {
+	return fmt.Sprintf("%02x-%s-%s-%02x", tp.version, tp.TraceID(), tp.SpanID(), tp.flags)
+}
+
+type traceparentKeyType struct{}
+
+var traceparentKey traceparentKeyType
+
+// WithTraceparent returns a copy of ctx that holds tp.
+func WithTraceparent(ctx context.Context, tp Traceparent) context.Context {
+	return context.WithValue(ctx, traceparentKey, tp)
+}
+
+// TraceparentFromContext returns the Traceparent stored in ctx by WithTraceparent.
+func TraceparentFromContext(ctx context.Context) (Traceparent, bool) {
+	tp, ok := ctx.Value(traceparentKey).(Traceparent)
+	return tp, ok
+}
+
+func decodeHex(s string, n int) ([]byte, error) {
+	if len(s) != n*2 || strings.ToLower(s) != s {
+		return nil, ErrInvalidTraceparent
+	}
+	return hex.DecodeString(s)
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
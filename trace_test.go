@@ -0,0 +1,86 @@
+package slogutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		s       string
+		invalid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", true},
+		{"", true},
+	}
+	for _, c := range cases {
+		tp, err := ParseTraceparent(c.s)
+		if c.invalid {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", c.s, tp)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", c.s, err)
+			continue
+		}
+		if tp.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" || tp.SpanID() != "00f067aa0ba902b7" {
+			t.Errorf("%q: unexpected ids %s %s", c.s, tp.TraceID(), tp.SpanID())
+		}
+	}
+	tp, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !tp.Sampled() || tp.String() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("unexpected traceparent %v sampled=%v", tp, tp.Sampled())
+	}
+}
+
+func TestMiddleware__WithContextExtractors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	middleware := NewMiddleware(
+		slog.NewJSONHandler,
+		MiddlewareOptions{
+			ContextExtractors: []ContextExtractorFunc{
+				TraceparentExtractor(),
+			},
+			Writer: buf,
+		},
+	)
+	logger := slog.New(middleware)
+	tp, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := With(context.Background(), slog.Int64("request_id", 12))
+	logger.InfoContext(ctx, "foo")
+	ctx = WithTraceparent(ctx, tp)
+	logger.InfoContext(ctx, "bar")
+	expected := []string{
+		`{"level":"INFO","msg":"foo","request_id":12}`,
+		`{"level":"INFO","msg":"bar","request_id":12,"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}`,
+	}
+	dec := json.NewDecoder(buf)
+	for i := range expected {
+		var actualObj, expectedObj map[string]interface{}
+		if err := dec.Decode(&actualObj); err != nil {
+			t.Fatalf("failed to decode line %d: %s", i, err)
+		}
+		if err := json.Unmarshal([]byte(expected[i]), &expectedObj); err != nil {
+			t.Fatalf("failed to unmarshal expected %q: %s", expected[i], err)
+		}
+		delete(actualObj, "time")
+		if !jsonEqual(actualObj, expectedObj) {
+			t.Errorf("expected %q, got %v", expected[i], actualObj)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package slogutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TransformerFactory creates a RecordTransformerFunc from the params of a TransformerConfig.
+type TransformerFactory func(params map[string]any) (RecordTransformerFunc, error)
+
+var (
+	transformersMu sync.RWMutex
+	transformers   = map[string]TransformerFactory{
+		"default":      newDefaultAttrsTransformer,
+		"drop":         newDropAttrsTransformer,
+		"rename":       newRenameAttrsTransformer,
+		"unique":       newUniqueAttrsTransformer,
+		"redact":       newRedactTransformer,
+		"legacy_level": newLegacyLevelTransformer,
+		"logfmt":       newLogfmtTransformer,
+		"time":         newTimeTransformer,
+		"source":       newSourceTransformer,
+	}
+)
+
+// RegisterTransformer makes a RecordTransformerFunc available to Config by name.
+// It panics if f is nil or the name is already registered.
+//
+// The built-in transformers are:
+//
+//	default       {"attrs": {"env": "prod"}}                             DefaultAttrs
+//	drop          {"keys": ["password", "http.authorization"]}           DropAttrs
+//	rename        {"keys": {"msg_id": "message_id"}}                     RenameAttrs
+//	unique        {"policy": "last_wins"}                                UniqueAttrsWithPolicy; first_wins, merge or suffix
+//	redact        {"keys": [], "key_patterns": [], "key_regexps": [],
+//	               "detectors": ["bearer_token", "aws_access_key", "credit_card", "email"],
+//	               "redact_message": true, "replacement": "***"}         Redact
+//	legacy_level  {"levels": {"DEBUG": "debug"}, "case_insensitive": true} ConvertLegacyLevel; levels default to DefaultLegacyLevels
+//	logfmt        {"message_key": "msg", "convert_level": true}           ParseLogfmt
+//	time          {"location": "UTC", "truncate": "1ms", "format": "epoch_millis"}
+//	                                                                     TimeIn, TruncateTime and FormatTime, in this order;
+//	                                                                     format is rfc3339, rfc3339nano, epoch_millis, epoch_seconds or a layout
+//	source        {"key": "source", "path": "module", "function": true,
+//	               "skip": 1, "skip_packages": ["github.com/acme/logwrap"]} AddSource; path is module, base or full
+func RegisterTransformer(name string, f TransformerFactory) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	if f == nil {
+		panic("slogutils: RegisterTransformer factory is nil")
+	}
+	if _, dup := transformers[name]; dup {
+		panic("slogutils: RegisterTransformer called twice for " + name)
+	}
+	transformers[name] = f
+}
+
+// Transformers returns the sorted names of the registered transformers.
+func Transformers() []string {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+	names := make([]string, 0, len(transformers))
+	for name := range transformers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTransformer creates the registered transformer of the given name with params.
+func NewTransformer(name string, params map[string]any) (RecordTransformerFunc, error) {
+	transformersMu.RLock()
+	f, ok := transformers[name]
+	transformersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("slogutils: unknown transformer %q", name)
+	}
+	t, err := f(params)
+	if err != nil {
+		return nil, fmt.Errorf("slogutils: transformer %q: %w", name, err)
+	}
+	return t, nil
+}
+
+// DecodeTransformerParams decodes params into v, which is usually a pointer to a struct with json tags.
+// Unknown params are an error.
+func DecodeTransformerParams(params map[string]any, v any) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func newDefaultAttrsTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Attrs map[string]any `json:"attrs"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(p.Attrs))
+	for k := range p.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]any, 0, len(keys))
+	for _, k := range keys {
+		args = append(args, slog.Any(k, p.Attrs[k]))
+	}
+	return DefaultAttrs(args...), nil
+}
+
+func newDropAttrsTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Keys []string `json:"keys"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	return DropAttrs(p.Keys...), nil
+}
+
+func newRenameAttrsTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Keys map[string]string `json:"keys"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	return RenameAttrs(p.Keys), nil
+}
+
+var duplicatePolicies = map[string]DuplicatePolicy{
+	"":           DuplicateLastWins,
+	"last_wins":  DuplicateLastWins,
+	"first_wins": DuplicateFirstWins,
+	"merge":      DuplicateMerge,
+	"suffix":     DuplicateSuffix,
+}
+
+func newUniqueAttrsTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Policy string `json:"policy"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	policy, ok := duplicatePolicies[p.Policy]
+	if !ok {
+		return nil, fmt.Errorf("invalid policy %q: must be last_wins, first_wins, merge or suffix", p.Policy)
+	}
+	return UniqueAttrsWithPolicy(policy), nil
+}
+
+var detectors = map[string]DetectorFunc{
+	"bearer_token":   DetectBearerToken,
+	"aws_access_key": DetectAWSAccessKey,
+	"credit_card":    DetectCreditCard,
+	"email":          DetectEmail,
+}
+
+func newRedactTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Keys          []string `json:"keys"`
+		KeyPatterns   []string `json:"key_patterns"`
+		KeyRegexps    []string `json:"key_regexps"`
+		Detectors     []string `json:"detectors"`
+		RedactMessage bool     `json:"redact_message"`
+		Replacement   string   `json:"replacement"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	opts := RedactOptions{
+		Keys:          p.Keys,
+		KeyPatterns:   p.KeyPatterns,
+		RedactMessage: p.RedactMessage,
+		Replacement:   p.Replacement,
+	}
+	for _, s := range p.KeyRegexps {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key regexp %q: %w", s, err)
+		}
+		opts.KeyRegexps = append(opts.KeyRegexps, re)
+	}
+	for _, name := range p.Detectors {
+		d, ok := detectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector %q: must be bearer_token, aws_access_key, credit_card or email", name)
+		}
+		opts.Detectors = append(opts.Detectors, d)
+	}
+	for _, pattern := range p.KeyPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+		}
+	}
+	return Redact(opts), nil
+}
+
+func newLegacyLevelTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Levels          map[string]slog.Level `json:"levels"`
+		CaseInsensitive bool                  `json:"case_insensitive"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Levels == nil {
+		p.Levels = make(map[string]slog.Level, len(DefaultLegacyLevels))
+		for k, v := range DefaultLegacyLevels {
+			p.Levels[k] = v
+		}
+		p.CaseInsensitive = true
+	}
+	return ConvertLegacyLevel(p.Levels, p.CaseInsensitive), nil
+}
+
+func newLogfmtTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		MessageKey   string `json:"message_key"`
+		ConvertLevel bool   `json:"convert_level"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	opts := LogfmtOptions{MessageKey: p.MessageKey}
+	if p.ConvertLevel {
+		opts.LevelMap = DefaultLegacyLevels
+	}
+	return ParseLogfmt(opts), nil
+}
+
+// timeFormats are the names of time formats accepted by the time transformer in addition to layouts.
+var timeFormats = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+}
+
+func newTimeTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Location string `json:"location"`
+		Truncate string `json:"truncate"`
+		Format   string `json:"format"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	var transformers []RecordTransformerFunc
+	if p.Location != "" {
+		loc, err := time.LoadLocation(p.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid location %q: %w", p.Location, err)
+		}
+		transformers = append(transformers, TimeIn(loc))
+	}
+	if p.Truncate != "" {
+		d, err := time.ParseDuration(p.Truncate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid truncate %q: %w", p.Truncate, err)
+		}
+		transformers = append(transformers, TruncateTime(d))
+	}
+	if p.Format != "" {
+		format, ok := timeFormats[p.Format]
+		if !ok {
+			format = p.Format
+		}
+		transformers = append(transformers, FormatTime(format))
+	}
+	return func(r slog.Record) slog.Record {
+		for _, t := range transformers {
+			r = t(r)
+		}
+		return r
+	}, nil
+}
+
+// sourcePaths are the names of SourcePath values accepted by the source transformer.
+var sourcePaths = map[string]SourcePath{
+	"":       SourcePathModule,
+	"module": SourcePathModule,
+	"base":   SourcePathBase,
+	"full":   SourcePathFull,
+}
+
+func newSourceTransformer(params map[string]any) (RecordTransformerFunc, error) {
+	var p struct {
+		Key          string   `json:"key"`
+		Path         string   `json:"path"`
+		Function     bool     `json:"function"`
+		Skip         int      `json:"skip"`
+		SkipPackages []string `json:"skip_packages"`
+	}
+	if err := DecodeTransformerParams(params, &p); err != nil {
+		return nil, err
+	}
+	path, ok := sourcePaths[p.Path]
+	if !ok {
+		return nil, fmt.Errorf("invalid path %q", p.Path)
+	}
+	return AddSource(SourceOptions{
+		Key:          p.Key,
+		Path:         path,
+		Function:     p.Function,
+		Skip:         p.Skip,
+		SkipPackages: p.SkipPackages,
+	}), nil
+}
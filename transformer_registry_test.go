@@ -0,0 +1,114 @@
+package slogutils
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTransformer(t *testing.T) {
+	cases := []struct {
+		name     string
+		params   map[string]any
+		message  string
+		expected map[string]string
+	}{
+		{"default", map[string]any{"attrs": map[string]any{"env": "prod", "user": "ignored"}}, "hello", map[string]string{"user": "alice", "token": "Bearer abc", "env": "prod"}},
+		{"drop", map[string]any{"keys": []any{"token"}}, "hello", map[string]string{"user": "alice"}},
+		{"rename", map[string]any{"keys": map[string]any{"user": "user_name"}}, "hello", map[string]string{"user_name": "alice", "token": "Bearer abc"}},
+		{"unique", map[string]any{"policy": "first_wins"}, "hello", map[string]string{"user": "alice", "token": "Bearer abc"}},
+		{"redact", map[string]any{"key_patterns": []any{"tok*"}}, "hello", map[string]string{"user": "alice", "token": RedactedValue}},
+		{"redact", map[string]any{"detectors": []any{"bearer_token"}}, "hello", map[string]string{"user": "alice", "token": RedactedValue}},
+		{"legacy_level", nil, "[debug] hello", map[string]string{"user": "alice", "token": "Bearer abc"}},
+		{"logfmt", map[string]any{"convert_level": true}, "level=debug hello id=1", map[string]string{"user": "alice", "token": "Bearer abc", "id": "1"}},
+		{"source", map[string]any{"path": "base", "function": true}, "hello", map[string]string{"user": "alice", "token": "Bearer abc"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			transformer, err := NewTransformer(c.name, c.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, c.message, 0)
+			r.AddAttrs(slog.String("user", "alice"), slog.String("token", "Bearer abc"))
+			actual := flattenRecordAttrs(transformer(r))
+			if len(actual) != len(c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+			for k, v := range c.expected {
+				if actual[k] != v {
+					t.Errorf("expected %s=%q, got %q", k, v, actual[k])
+				}
+			}
+		})
+	}
+}
+
+func TestNewTransformer__Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		params map[string]any
+		err    string
+	}{
+		{"unknown", nil, `unknown transformer "unknown"`},
+		{"drop", map[string]any{"key": []any{"x"}}, `unknown field "key"`},
+		{"unique", map[string]any{"policy": "random"}, "invalid policy"},
+		{"redact", map[string]any{"key_patterns": []any{"["}}, "invalid key pattern"},
+		{"redact", map[string]any{"key_regexps": []any{"("}}, "invalid key regexp"},
+		{"redact", map[string]any{"detectors": []any{"ssn"}}, "unknown detector"},
+		{"legacy_level", map[string]any{"levels": map[string]any{"X": "loud"}}, "legacy_level"},
+		{"source", map[string]any{"path": "relative"}, "invalid path"},
+	}
+	for _, c := range cases {
+		if _, err := NewTransformer(c.name, c.params); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestRegisterTransformer(t *testing.T) {
+	RegisterTransformer("test_upper_message", func(params map[string]any) (RecordTransformerFunc, error) {
+		return func(r slog.Record) slog.Record {
+			c := r.Clone()
+			c.Message = strings.ToUpper(c.Message)
+			return c
+		}, nil
+	})
+	transformer, err := NewTransformer("test_upper_message", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if r := transformer(slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)); r.Message != "HELLO" {
+		t.Errorf("expected HELLO, got %s", r.Message)
+	}
+	found := false
+	for _, name := range Transformers() {
+		found = found || name == "test_upper_message"
+	}
+	if !found {
+		t.Errorf("expected test_upper_message in %v", Transformers())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	RegisterTransformer("drop", newDropAttrsTransformer)
+}
+
+func TestNewTransformer__Time(t *testing.T) {
+	transformer, err := NewTransformer("time", map[string]any{"location": "UTC", "truncate": "1s", "format": "rfc3339nano"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	r := slog.NewRecord(time.Date(2023, 8, 15, 9, 0, 0, 999, time.FixedZone("JST", 9*60*60)), slog.LevelInfo, "hello", 0)
+	if actual := flattenRecordAttrs(transformer(r))["time"]; actual != "2023-08-15T00:00:00Z" {
+		t.Errorf("expected 2023-08-15T00:00:00Z, got %s", actual)
+	}
+	for _, params := range []map[string]any{{"location": "Mars/Olympus"}, {"truncate": "soon"}} {
+		if _, err := NewTransformer("time", params); err == nil {
+			t.Errorf("%v: expected error", params)
+		}
+	}
+}